@@ -21,6 +21,9 @@ If a file is specified, yugo will render that file to outfile or stdout.
 		{Name: "site", FlagType: cmdflag.FlagTypeString, DefaultValue: ".", Usage: "Path to site directory (default: current directory)", Predictor: cmdflag.PredictDirs("*")},
 		{Name: "outdir", FlagType: cmdflag.FlagTypeString, DefaultValue: "", Usage: "Path to out directory (default: ./public)", Predictor: cmdflag.PredictDirs("*")},
 		{Name: "base-template", FlagType: cmdflag.FlagTypeString, DefaultValue: "", Usage: "Base template name (default: base.html)", Predictor: cmdflag.PredictNothing},
+		{Name: "strict", FlagType: cmdflag.FlagTypeBool, DefaultValue: false, Usage: "Fail the build on warnings, missing template keys and unsafe template output"},
+		{Name: "drafts", FlagType: cmdflag.FlagTypeBool, DefaultValue: false, Usage: "Render pages marked as Draft"},
+		{Name: "base-url", FlagType: cmdflag.FlagTypeString, DefaultValue: "", Usage: "URL the site is published at, used for permalinks", Predictor: cmdflag.PredictNothing},
 	},
 	Args: cmdflag.PredictOr(cmdflag.PredictFiles("*.md"), cmdflag.PredictFiles("*.html")),
 }
@@ -35,6 +38,9 @@ func runBuild(ctx context.Context, cmd *cmdflag.Command, args []string) {
 		"site":          &ropts.SiteDir,
 		"outdir":        &ropts.OutDir,
 		"base-template": &ropts.BaseTemplate,
+		"strict":        &ropts.Strict,
+		"drafts":        &ropts.BuildDrafts,
+		"base-url":      &ropts.BaseURL,
 	})
 	_ = fs.Parse(args)
 	if err := opts.MergeConfig(); err != nil {
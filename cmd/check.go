@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"log"
+
+	"github.com/msolo/cmdflag"
+	"github.com/msolo/yugo/internal/build"
+	"github.com/msolo/yugo/internal/check"
+)
+
+var cmdCheck = &cmdflag.Command{
+	Name:      "check",
+	Run:       runCheck,
+	UsageLine: "yugo check [flags]",
+	UsageLong: `Build a yugo site and check the rendered output for broken links and anchors.
+
+Every internal href, src and srcset is resolved against the output directory.
+Anchors are resolved against the element IDs of the target page.
+`,
+	Flags: append([]cmdflag.Flag{
+		{Name: "build", FlagType: cmdflag.FlagTypeBool, DefaultValue: true, Usage: "Build the site before checking (default: enabled)"},
+	}, cmdBuild.Flags...),
+	// We have no positional args
+	Args: cmdflag.PredictNothing,
+}
+
+func runCheck(ctx context.Context, cmd *cmdflag.Command, args []string) {
+	opts, ropts := build.NewOptions()
+
+	doBuild := true
+	fs := cmd.BindFlagSet(map[string]any{
+		"build":         &doBuild,
+		"tidy-html":     &ropts.TidyHTML,
+		"site":          &ropts.SiteDir,
+		"outdir":        &ropts.OutDir,
+		"base-template": &ropts.BaseTemplate,
+		"strict":        &ropts.Strict,
+		"drafts":        &ropts.BuildDrafts,
+		"base-url":      &ropts.BaseURL,
+	})
+	_ = fs.Parse(args)
+	if err := opts.MergeConfig(); err != nil {
+		log.Fatal(err)
+	}
+
+	if doBuild {
+		build.Run(opts, nil)
+	}
+	check.Run(opts)
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/msolo/cmdflag"
+	"github.com/msolo/yugo/internal/build"
+)
+
+var cmdGraph = &cmdflag.Command{
+	Name:      "graph",
+	Run:       runGraph,
+	UsageLine: "yugo graph [flags]",
+	UsageLong: `Print the internal link graph of a yugo site.
+
+Each page is a node named by its path under content, and each Markdown link
+between pages is an edge labelled with the link text. The output is DOT for
+Graphviz or JSON.
+`,
+	Flags: append([]cmdflag.Flag{
+		{Name: "format", FlagType: cmdflag.FlagTypeString, DefaultValue: "dot", Usage: "Output format: dot or json", Predictor: cmdflag.PredictSet("dot", "json")},
+	}, cmdBuild.Flags...),
+	// We have no positional args
+	Args: cmdflag.PredictNothing,
+}
+
+func runGraph(ctx context.Context, cmd *cmdflag.Command, args []string) {
+	opts, ropts := build.NewOptions()
+
+	format := "dot"
+	// Every flag inherited from cmdBuild must be bound here too.
+	fs := cmd.BindFlagSet(map[string]any{
+		"format":        &format,
+		"tidy-html":     &ropts.TidyHTML,
+		"site":          &ropts.SiteDir,
+		"outdir":        &ropts.OutDir,
+		"base-template": &ropts.BaseTemplate,
+		"strict":        &ropts.Strict,
+		"drafts":        &ropts.BuildDrafts,
+		"base-url":      &ropts.BaseURL,
+	})
+	_ = fs.Parse(args)
+	if err := opts.MergeConfig(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := build.WriteGraph(opts, os.Stdout, format); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -12,13 +12,17 @@ var cmdMain = &cmdflag.Command{
 Commands:
   init    Initialize a new site
   build   Build a site
-  serve   Serve a site with live reload`,
+  serve   Serve a site with live reload
+  check   Check a built site for broken links
+  graph   Print the internal link graph of a site`,
 }
 
 var subcommands = []*cmdflag.Command{
 	cmdInit,
 	cmdBuild,
 	cmdServe,
+	cmdCheck,
+	cmdGraph,
 }
 
 // Commands returns the root command and all subcommands for use by main.
@@ -26,13 +26,18 @@ var cmdServe = &cmdflag.Command{
 func runServe(ctx context.Context, cmd *cmdflag.Command, args []string) {
 	opts, ropts := build.NewOptions()
 
+	// Every flag inherited from cmdBuild must be bound here too.
 	fs := cmd.BindFlagSet(map[string]any{
-		"host":        &ropts.Host,
-		"port":        &ropts.Port,
-		"live-reload": &ropts.LiveReload,
-		"tidy-html":   &ropts.TidyHTML,
-		"site":        &ropts.SiteDir,
-		"outdir":      &ropts.OutDir,
+		"host":          &ropts.Host,
+		"port":          &ropts.Port,
+		"live-reload":   &ropts.LiveReload,
+		"tidy-html":     &ropts.TidyHTML,
+		"site":          &ropts.SiteDir,
+		"outdir":        &ropts.OutDir,
+		"base-template": &ropts.BaseTemplate,
+		"strict":        &ropts.Strict,
+		"drafts":        &ropts.BuildDrafts,
+		"base-url":      &ropts.BaseURL,
 	})
 
 	_ = fs.Parse(args)
@@ -5,6 +5,7 @@ go 1.24.0
 require github.com/yuin/goldmark v1.7.13
 
 require (
+	github.com/alecthomas/chroma/v2 v2.23.1
 	github.com/fsnotify/fsnotify v1.9.0
 	github.com/ianbruene/go-difflib v1.3.0
 	github.com/msolo/cmdflag v0.0.0-20251130010113-14886ba70716
@@ -13,6 +14,7 @@ require (
 )
 
 require (
+	github.com/dlclark/regexp2 v1.11.5 // indirect
 	github.com/hashicorp/errwrap v1.0.0 // indirect
 	github.com/hashicorp/go-multierror v1.0.0 // indirect
 	github.com/posener/complete v1.2.1 // indirect
@@ -0,0 +1,142 @@
+package build
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// alertTitles are the GitHub alert types and their titles.
+var alertTitles = map[string]string{
+	"note":      "Note",
+	"tip":       "Tip",
+	"important": "Important",
+	"warning":   "Warning",
+	"caution":   "Caution",
+}
+
+// KindAlert is the ast.NodeKind of Alert.
+var KindAlert = ast.NewNodeKind("Alert")
+
+// Alert is a GitHub alert, a top-level blockquote starting with a line like
+// [!NOTE]. Its children are the blocks of the blockquote without that line.
+type Alert struct {
+	ast.BaseBlock
+	AlertType string // like "note"
+}
+
+func (n *Alert) Kind() ast.NodeKind {
+	return KindAlert
+}
+
+func (n *Alert) Title() string {
+	return alertTitles[n.AlertType]
+}
+
+func (n *Alert) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"AlertType": n.AlertType}, nil)
+}
+
+// Alerts renders GitHub alerts as
+//
+//	<div class="markdown-alert markdown-alert-note">
+//	<p class="markdown-alert-title">Note</p>
+//	...
+//	</div>
+//
+// The _render/alert.html hook replaces this, see RenderHooks.
+type Alerts struct{}
+
+func (e Alerts) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(alertTransformer{}, 0),
+	))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(alertRenderer{}, 500),
+	))
+}
+
+type alertTransformer struct{}
+
+func (t alertTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	// Like GitHub, alerts can't be nested in other elements.
+	for n := node.FirstChild(); n != nil; {
+		next := n.NextSibling()
+		if quote, ok := n.(*ast.Blockquote); ok {
+			if alert := newAlert(quote, source); alert != nil {
+				node.ReplaceChild(node, quote, alert)
+			}
+		}
+		n = next
+	}
+}
+
+// newAlert moves the content of a blockquote that starts with an alert
+// marker into an Alert, or returns nil if it has no marker.
+func newAlert(quote *ast.Blockquote, source []byte) *Alert {
+	para, ok := quote.FirstChild().(*ast.Paragraph)
+	if !ok || para.Lines().Len() == 0 {
+		return nil
+	}
+	first := para.Lines().At(0)
+	marker := string(bytes.TrimSpace(first.Value(source)))
+	if !strings.HasPrefix(marker, "[!") || !strings.HasSuffix(marker, "]") {
+		return nil
+	}
+	typ := strings.ToLower(marker[2 : len(marker)-1])
+	if _, ok := alertTitles[typ]; !ok {
+		return nil
+	}
+
+	// Drop the inline nodes of the marker line, then the paragraph if
+	// nothing is left.
+	for c := para.FirstChild(); c != nil; {
+		next := c.NextSibling()
+		t, ok := c.(*ast.Text)
+		if !ok || t.Segment.Start >= first.Stop {
+			break
+		}
+		para.RemoveChild(para, c)
+		c = next
+	}
+	lines := text.NewSegments()
+	for i := 1; i < para.Lines().Len(); i++ {
+		lines.Append(para.Lines().At(i))
+	}
+	para.SetLines(lines)
+	if !para.HasChildren() {
+		quote.RemoveChild(quote, para)
+	}
+
+	alert := &Alert{AlertType: typ}
+	for c := quote.FirstChild(); c != nil; {
+		next := c.NextSibling()
+		alert.AppendChild(alert, c)
+		c = next
+	}
+	return alert
+}
+
+type alertRenderer struct{}
+
+func (r alertRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindAlert, r.render)
+}
+
+func (r alertRenderer) render(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	alert := n.(*Alert)
+	if entering {
+		_, _ = w.WriteString(`<div class="markdown-alert markdown-alert-` + alert.AlertType + "\">\n")
+		_, _ = w.WriteString(`<p class="markdown-alert-title">` + alert.Title() + "</p>\n")
+	} else {
+		_, _ = w.WriteString("</div>\n")
+	}
+	return ast.WalkContinue, nil
+}
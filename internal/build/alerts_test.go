@@ -0,0 +1,83 @@
+package build
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderContentAlerts(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{.Content}}`,
+		"content/page.md": "> [!NOTE]\n> Useful *information*.\n\n" +
+			"> [!warning]\n>\n> First.\n>\n> Second.\n\n" +
+			"> [!TIP]\n\n" +
+			"> [!UNKNOWN]\n> Text.\n\n" +
+			"> [!NOTE] Same line.\n\n" +
+			"- > [!NOTE]\n  > In a list.\n",
+		"content/off.md": "---\n{\"Markdown\": {\"Alerts\": false}}\n---\n> [!NOTE]\n> Text.\n",
+	})
+
+	opts := &Options{&RawOptions{SiteDir: tmp}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<div class="markdown-alert markdown-alert-note">
+<p class="markdown-alert-title">Note</p>
+<p>Useful <em>information</em>.</p>
+</div>
+<div class="markdown-alert markdown-alert-warning">
+<p class="markdown-alert-title">Warning</p>
+<p>First.</p>
+<p>Second.</p>
+</div>
+<div class="markdown-alert markdown-alert-tip">
+<p class="markdown-alert-title">Tip</p>
+</div>
+<blockquote>
+<p>[!UNKNOWN]
+Text.</p>
+</blockquote>
+<blockquote>
+<p>[!NOTE] Same line.</p>
+</blockquote>
+<ul>
+<li>
+<blockquote>
+<p>[!NOTE]
+In a list.</p>
+</blockquote>
+</li>
+</ul>
+`
+	if out := readFile(t, filepath.Join(tmp, "public/page.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+
+	expected = "<blockquote>\n<p>[!NOTE]\nText.</p>\n</blockquote>\n"
+	if out := readFile(t, filepath.Join(tmp, "public/off.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+}
+
+func TestRenderContentAlertHook(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":                   `{}`,
+		"templates/base.html":          `{{.Content}}`,
+		"templates/_render/alert.html": `<aside class="{{.Type}}"><b>{{.Title}}</b>{{.Text}}</aside>` + "\n",
+		"content/page.md":              "> [!CAUTION]\n> Hot **surface**.\n",
+	})
+
+	opts := &Options{&RawOptions{SiteDir: tmp}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "<aside class=\"caution\"><b>Caution</b><p>Hot <strong>surface</strong>.</p>\n</aside>\n"
+	if out := readFile(t, filepath.Join(tmp, "public/page.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+}
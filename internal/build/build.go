@@ -2,35 +2,57 @@ package build
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
-	"io/fs"
 	"log"
 	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/msolo/jsonr"
 	"github.com/msolo/yugo/internal/htmltidy"
 	"github.com/msolo/yugo/internal/resources"
 	"github.com/yuin/goldmark"
-	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/parser"
-	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
 	"github.com/yuin/goldmark/util"
 )
 
 // RawOptions can be read from the CLI or config, but should't be used by the
 // rest of the application.
 type RawOptions struct {
-	Host         string `json:"-"`
-	Port         int    `json:"-"`
-	SiteDir      string `json:"-"`
-	OutDir       string `json:"OutDir"`
-	LiveReload   bool   `json:"-"`
-	TidyHTML     bool   `json:"-"`
-	BaseTemplate string `json:"-"`
+	Host          string `json:"-"`
+	Port          int    `json:"-"`
+	SiteDir       string `json:"-"`
+	OutDir        string `json:"OutDir"`
+	LiveReload    bool   `json:"-"`
+	TidyHTML      bool   `json:"-"`
+	BaseTemplate  string `json:"-"`
+	Strict        bool   `json:"Strict"`
+	BuildDrafts   bool   `json:"BuildDrafts"`
+	Timezone      string `json:"Timezone"`
+	Schema        Schema `json:"Schema"`
+	SummaryLength int    `json:"SummaryLength"`
+	BaseURL       string `json:"BaseURL"`
+
+	DeriveTitle       bool `json:"DeriveTitle"`
+	StripTitleHeading bool `json:"StripTitleHeading"`
+	DeriveDescription bool `json:"DeriveDescription"`
+
+	HeadingIDs     string         `json:"HeadingIDs"`
+	Markdown       MarkdownConfig `json:"Markdown"`
+	HighlightStyle string         `json:"HighlightStyle"`
+	RepoURL        string         `json:"RepoURL"`
+	RepoDir        string         `json:"RepoDir"`
+
+	Sections map[string]SectionConfig `json:"Sections"`
+	Related  RelatedConfig            `json:"Related"`
 }
 
 // Allow certain options read from config to be merged with values from
@@ -42,6 +64,27 @@ func (o1 *RawOptions) mergeConfig(o2 *RawOptions) {
 	if o1.BaseTemplate == "" {
 		o1.BaseTemplate = o2.BaseTemplate
 	}
+	// Booleans can be enabled from either place, but not disabled.
+	o1.Strict = o1.Strict || o2.Strict
+	o1.BuildDrafts = o1.BuildDrafts || o2.BuildDrafts
+	o1.DeriveTitle = o1.DeriveTitle || o2.DeriveTitle
+	o1.StripTitleHeading = o1.StripTitleHeading || o2.StripTitleHeading
+	o1.DeriveDescription = o1.DeriveDescription || o2.DeriveDescription
+	if o1.Timezone == "" {
+		o1.Timezone = o2.Timezone
+	}
+	o1.Schema = o2.Schema
+	o1.SummaryLength = o2.SummaryLength
+	if o1.BaseURL == "" {
+		o1.BaseURL = o2.BaseURL
+	}
+	o1.HeadingIDs = o2.HeadingIDs
+	o1.Markdown = o2.Markdown
+	o1.HighlightStyle = o2.HighlightStyle
+	o1.RepoURL = o2.RepoURL
+	o1.RepoDir = o2.RepoDir
+	o1.Sections = o2.Sections
+	o1.Related = o2.Related
 }
 
 type Options struct {
@@ -54,6 +97,36 @@ func (o *Options) MergeConfig() error {
 		return err
 	}
 	o.rawOptions.mergeConfig(o2)
+	if o.rawOptions.Timezone != "" {
+		if _, err := time.LoadLocation(o.rawOptions.Timezone); err != nil {
+			return fmt.Errorf("invalid Timezone: %w", err)
+		}
+	}
+	if o.rawOptions.BaseURL != "" {
+		u, err := url.Parse(o.rawOptions.BaseURL)
+		if err != nil {
+			return fmt.Errorf("invalid BaseURL: %w", err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid BaseURL: %q must be absolute", o.rawOptions.BaseURL)
+		}
+	}
+	switch o.rawOptions.HeadingIDs {
+	case "", headingIDsGoldmark, headingIDsGitHub:
+	default:
+		return fmt.Errorf("invalid HeadingIDs: %q is not %q or %q", o.rawOptions.HeadingIDs, headingIDsGoldmark, headingIDsGitHub)
+	}
+	if err := o.rawOptions.Markdown.check(); err != nil {
+		return fmt.Errorf("invalid Markdown: %w", err)
+	}
+	if _, ok := styles.Registry[o.HighlightStyle()]; !ok {
+		return fmt.Errorf("invalid HighlightStyle: unknown style %q", o.rawOptions.HighlightStyle)
+	}
+	for name, sc := range o.rawOptions.Sections {
+		if err := sc.check(); err != nil {
+			return fmt.Errorf("invalid Sections.%s: %w", name, err)
+		}
+	}
 	return nil
 }
 
@@ -105,6 +178,135 @@ func (o Options) BaseTemplate() string {
 	return baseTemplate
 }
 
+// Strict turns warnings into build failures.
+func (o Options) Strict() bool {
+	return o.rawOptions.Strict
+}
+
+// BuildDrafts renders pages marked as Draft in their frontmatter.
+func (o Options) BuildDrafts() bool {
+	return o.rawOptions.BuildDrafts
+}
+
+// DeriveTitle fills in a missing Title from the first H1 of a Markdown page.
+func (o Options) DeriveTitle() bool {
+	return o.rawOptions.DeriveTitle
+}
+
+// StripTitleHeading removes the H1 used by DeriveTitle from the body.
+func (o Options) StripTitleHeading() bool {
+	return o.rawOptions.StripTitleHeading
+}
+
+// DeriveDescription fills in a missing Description from the first paragraph
+// of a Markdown page.
+func (o Options) DeriveDescription() bool {
+	return o.rawOptions.DeriveDescription
+}
+
+// HeadingIDs is the style of the IDs generated for headings.
+func (o Options) HeadingIDs() string {
+	if o.rawOptions.HeadingIDs == "" {
+		return headingIDsGoldmark
+	}
+	return o.rawOptions.HeadingIDs
+}
+
+// RepoURL is the URL template for links to files outside the content
+// directory, with {path} and {line} placeholders.
+func (o Options) RepoURL() string {
+	return o.rawOptions.RepoURL
+}
+
+// RepoDir is the repository root that {path} in RepoURL is relative to. It
+// defaults to the closest directory above the site with a .git entry, or the
+// site directory itself.
+func (o Options) RepoDir() string {
+	if o.rawOptions.RepoDir != "" {
+		return cleanJoin(o.rawOptions.SiteDir, o.rawOptions.RepoDir)
+	}
+	dir, err := filepath.Abs(o.SiteDir())
+	if err != nil {
+		return o.SiteDir()
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		if filepath.Dir(d) == d {
+			return o.SiteDir()
+		}
+	}
+}
+
+// Markdown returns the Markdown settings for a page: the defaults, then
+// yugo.jsonr, then the page's frontmatter.
+func (o Options) Markdown(page *Page) MarkdownConfig {
+	return defaultMarkdownConfig.overlay(o.rawOptions.Markdown).overlay(page.markdown)
+}
+
+// HighlightStyle is the chroma style of the stylesheet for highlighted
+// code.
+func (o Options) HighlightStyle() string {
+	if o.rawOptions.HighlightStyle == "" {
+		return defaultHighlightStyle
+	}
+	return o.rawOptions.HighlightStyle
+}
+
+// Location is used for frontmatter dates that don't specify a zone.
+func (o Options) Location() *time.Location {
+	if o.rawOptions.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(o.rawOptions.Timezone)
+	if err != nil {
+		// MergeConfig has already validated the name.
+		return time.UTC
+	}
+	return loc
+}
+
+// BaseURL is where the site is published. Without one, permalinks are
+// relative to the site root.
+func (o Options) BaseURL() *url.URL {
+	u, err := url.Parse(o.rawOptions.BaseURL)
+	if err != nil {
+		// MergeConfig has already validated the URL.
+		return &url.URL{}
+	}
+	return u
+}
+
+// SummaryLength is the number of words in an automatic summary.
+func (o Options) SummaryLength() int {
+	if o.rawOptions.SummaryLength > 0 {
+		return o.rawOptions.SummaryLength
+	}
+	return 70
+}
+
+// SectionConfig returns the page ordering for a section, falling back to
+// the "*" entry of Sections.
+func (o Options) SectionConfig(section string) SectionConfig {
+	if sc, ok := o.rawOptions.Sections[section]; ok {
+		return sc
+	}
+	return o.rawOptions.Sections[allSections]
+}
+
+// Related returns the settings for .Page.Related with defaults filled in.
+func (o Options) Related() RelatedConfig {
+	rc := o.rawOptions.Related
+	if rc.Limit <= 0 {
+		rc.Limit = 5
+	}
+	if rc.Weights == nil {
+		rc.Weights = defaultRelatedWeights
+	}
+	return rc
+}
+
 func cleanJoin(head, tail string) string {
 	return filepath.Clean(filepath.Join(head, tail))
 }
@@ -135,7 +337,10 @@ func ReadConfig(siteDir string) (*RawOptions, error) {
 	return configOpts, nil
 }
 
-func Run(opts *Options, args []string) {
+// Run builds the site, or renders a single content file given in args. It
+// returns the files outside the site directory that the site was built
+// from, like included Markdown, so that they can be watched for changes.
+func Run(opts *Options, args []string) []string {
 	if len(args) > 0 {
 		tmpl, err := loadTemplates(opts)
 		if err != nil {
@@ -145,8 +350,12 @@ func Run(opts *Options, args []string) {
 		if err != nil {
 			rel = filepath.Base(args[0])
 		}
+		siteConfig, err := readSiteConfig(opts)
+		if err != nil {
+			log.Fatalf("site config load failed: %s\n", err)
+		}
 
-		out, err := renderFile(args[0], rel, tmpl, opts, nil)
+		out, err := renderFile(args[0], rel, tmpl, opts, siteConfig)
 		if err != nil {
 			log.Fatalf("failed: %s\n", err)
 		}
@@ -157,12 +366,13 @@ func Run(opts *Options, args []string) {
 		if err := os.WriteFile(outPath, []byte(out), 0644); err != nil {
 			log.Fatalf("unable to write %s: %s\n", outPath, err)
 		}
-		return
+		return nil
 	}
 
 	fmt.Println("Building site...")
 
-	if err := renderContent(opts); err != nil {
+	site, err := renderSite(opts)
+	if err != nil {
 		log.Fatal("render content failed: ", err)
 	}
 
@@ -181,12 +391,15 @@ func Run(opts *Options, args []string) {
 	}
 
 	fmt.Println("Build complete.")
+	return site.dependencies(opts.SiteDir())
 }
 
 func loadTemplates(opts *Options) (*template.Template, error) {
 	tl := TemplateLoader{
 		TemplateDir: opts.TemplatesDir(),
 		StaticDir:   opts.StaticDir(),
+		BuiltinFS:   resources.TemplatesFS,
+		Strict:      opts.Strict(),
 	}
 
 	tmpl, err := tl.Load()
@@ -196,152 +409,332 @@ func loadTemplates(opts *Options) (*template.Template, error) {
 	return tmpl, nil
 }
 
-func renderContent(opts *Options) error {
+func readSiteConfig(opts *Options) (map[string]any, error) {
 	sitePath := filepath.Join(opts.SiteDir(), "site.jsonr")
 	siteConfig := map[string]any{}
 	raw, err := os.ReadFile(sitePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if err := jsonr.Unmarshal(raw, &siteConfig); err != nil {
-		return err
+		return nil, err
 	}
+	return siteConfig, nil
+}
 
-	// Remove the output directory entirely to ensure clean output every time.
-	if err := os.RemoveAll(opts.OutDir()); err != nil {
-		return err
-	}
-	if err := os.MkdirAll(opts.OutDir(), 0755); err != nil {
-		return err
+func renderContent(opts *Options) error {
+	_, err := renderSite(opts)
+	return err
+}
+
+// renderSite writes every page to the output directory and returns the site
+// it rendered.
+func renderSite(opts *Options) (*site, error) {
+	siteConfig, err := readSiteConfig(opts)
+	if err != nil {
+		return nil, err
 	}
 
+	// Templates are needed first for Markdown render hooks.
 	tmpl, err := loadTemplates(opts)
 	if err != nil {
-		return fmt.Errorf("template load failed: %w", err)
+		return nil, fmt.Errorf("template load failed: %w", err)
 	}
 
-	return filepath.WalkDir(opts.ContentDir(), func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return fmt.Errorf("walk dir failed: %w", err)
-		}
-		if d.IsDir() {
-			return nil
-		}
-
-		if d.Type()&fs.ModeSymlink != 0 {
-			return fmt.Errorf("symlinks not handled: %s", path)
-		}
+	site, err := loadSite(opts, siteConfig, tmpl)
+	if err != nil {
+		return nil, err
+	}
 
-		if !shouldProcessFile(path) {
-			return nil
-		}
+	// Remove the output directory entirely to ensure clean output every time.
+	if err := os.RemoveAll(opts.OutDir()); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(opts.OutDir(), 0755); err != nil {
+		return nil, err
+	}
 
-		ext := strings.ToLower(filepath.Ext(path))
-		rel, _ := filepath.Rel(opts.ContentDir(), path)
-		outPath := filepath.Join(opts.OutDir(), rel)
-		if ext == ".md" {
-			outPath = outPath[:len(outPath)-len(ext)] + ".html"
-		}
+	// In strict mode, keep going so every failure shows up in one summary.
+	buildErrs := BuildErrors{}
 
+	for _, page := range site.pages {
+		outPath := filepath.Join(opts.OutDir(), page.outPath)
 		if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
-			return fmt.Errorf("dir create failed: %w", err)
+			return nil, fmt.Errorf("dir create failed: %w", err)
 		}
 
-		out, err := renderFile(path, rel, tmpl, opts, siteConfig)
+		out, err := renderPage(page, tmpl, opts, site)
 		if err != nil {
-			return err
+			if opts.Strict() {
+				buildErrs = append(buildErrs, PageError{Source: page.relPath, Err: err})
+				continue
+			}
+			return nil, PageError{Source: page.relPath, Err: err}
 		}
 
 		if err := os.WriteFile(outPath, []byte(out), 0644); err != nil {
-			return fmt.Errorf("unable to write %s: %w", outPath, err)
+			return nil, fmt.Errorf("unable to write %s: %w", outPath, err)
 		}
 		fmt.Println("→", outPath)
+	}
+	if len(buildErrs) > 0 {
+		return nil, buildErrs
+	}
+
+	if site.highlight || *opts.Markdown(&Page{}).Highlight {
+		if err := writeHighlightCSS(opts); err != nil {
+			return nil, err
+		}
+	}
+	return site, nil
+}
+
+// writeHighlightCSS writes the stylesheet for highlighted code, unless the
+// static directory has one to use instead.
+func writeHighlightCSS(opts *Options) error {
+	if _, err := os.Stat(filepath.Join(opts.StaticDir(), highlightCSSFile)); err == nil {
 		return nil
-	})
+	}
+	outPath := filepath.Join(opts.OutDir(), highlightCSSFile)
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return fmt.Errorf("dir create failed: %w", err)
+	}
+	b := &bytes.Buffer{}
+	if err := WriteHighlightCSS(b, opts.HighlightStyle()); err != nil {
+		return err
+	}
+	if err := os.WriteFile(outPath, b.Bytes(), 0644); err != nil {
+		return fmt.Errorf("unable to write %s: %w", outPath, err)
+	}
+	fmt.Println("→", outPath)
+	return nil
 }
 
-func renderFile(path string, relPath string, tmpl *template.Template, opts *Options, siteConfig map[string]any) (string, error) {
-	src, err := os.ReadFile(path)
+// loadSite loads and converts every page so that all of the site's content
+// is available before any template is executed. Pages that fail are reported
+// as BuildErrors, along with a site made of the rest.
+func loadSite(opts *Options, siteConfig map[string]any, tmpl *template.Template) (*site, error) {
+	buildErrs := BuildErrors{}
+	pages, err := loadPages(opts)
+	if err != nil && !errors.As(err, &buildErrs) {
+		return nil, err
+	}
+	if opts.DeriveTitle() {
+		// Titles are needed for wiki links before any page is converted.
+		for _, page := range pages {
+			deriveTitle(page, opts)
+		}
+	}
+	site := newSite(siteConfig, pages)
+	site.templates = tmpl
+
+	for _, page := range site.pages {
+		if err := convertPage(page, opts, site); err != nil {
+			buildErrs = append(buildErrs, PageError{Source: page.relPath, Err: err})
+		}
+	}
+
+	for _, page := range site.pages {
+		site.setAncestors(page)
+	}
+	site.setPrevNext(opts)
+	site.setLinks()
+	site.setRelated(opts.Related())
+
+	site.menus, err = loadMenus(site.config, site.pages)
 	if err != nil {
+		return nil, err
+	}
+	if len(buildErrs) > 0 {
+		return site, buildErrs
+	}
+	return site, nil
+}
+
+// renderFile renders a single content file in the context of the rest of the
+// site. The file does not need to be part of the site, and problems with
+// other pages are only warnings.
+func renderFile(path string, relPath string, tmpl *template.Template, opts *Options, siteConfig map[string]any) (string, error) {
+	site, err := loadSite(opts, siteConfig, tmpl)
+	buildErrs := BuildErrors{}
+	if err != nil && !errors.As(err, &buildErrs) {
 		return "", err
 	}
+	for _, pe := range buildErrs {
+		if pe.Source == relPath {
+			return "", pe
+		}
+		fmt.Fprintf(os.Stderr, "WARN: %s\n", pe)
+	}
 
-	page, err := ParsePage(src)
+	page, ok := site.byPath[relPath]
+	if !ok {
+		page, err = loadPage(path, relPath, opts)
+		if err != nil {
+			return "", PageError{Source: relPath, Err: err}
+		}
+		if err := convertPage(page, opts, site); err != nil {
+			return "", PageError{Source: relPath, Err: err}
+		}
+		site.setAncestors(page)
+	}
+	out, err := renderPage(page, tmpl, opts, site)
 	if err != nil {
-		return "", err
+		return "", PageError{Source: relPath, Err: err}
 	}
+	return out, nil
+}
 
-	ext := strings.ToLower(filepath.Ext(path))
+// convertPage renders the body of a page to HTML and computes everything
+// derived from it.
+func convertPage(page *Page, opts *Options, site *site) error {
+	ext := strings.ToLower(filepath.Ext(page.path))
 
-	htmlStr := ""
+	htmlStr := ""     // the body, or the part after a <!--more--> divider
+	summaryHTML := "" // the part before the divider
+	hasDivider := false
 	tocItems := []TOCItem{}
+	page.links = nil
+	page.includes = nil
+
+	linkRewriter := LinkRewriter{
+		SiteDir:    opts.SiteDir(),
+		ContentDir: opts.ContentDir(),
+		Pages:      site.byPath,
+		Warn:       page.warn,
+		Link:       page.addLink,
+		RepoURL:    opts.RepoURL(),
+		RepoDir:    opts.RepoDir(),
+	}
 
 	if ext == ".md" {
 		htmlBuf := &bytes.Buffer{}
 		tocExt := &TOCExtension{Items: &tocItems}
 
-		md := goldmark.New(
-			goldmark.WithExtensions(
-				extension.GFM,
-				extension.Typographer,
-				tocExt,
-			),
-			goldmark.WithRendererOptions(
-				html.WithUnsafe(), // This option allows raw HTML rendering
-			),
-			goldmark.WithParserOptions(
-				parser.WithAutoHeadingID(),
-				parser.WithASTTransformers(
-					util.Prioritized(
-						LinkRewriter{
-							SiteDir:    opts.SiteDir(),
-							ContentDir: opts.ContentDir(),
-						},
-						100,
-					),
-				),
+		transformers := []util.PrioritizedValue{
+			util.Prioritized(linkRewriter, 100),
+			util.Prioritized(
+				PageMetaTransformer{
+					Page:        page,
+					Title:       opts.DeriveTitle(),
+					StripTitle:  opts.StripTitleHeading(),
+					Description: opts.DeriveDescription(),
+				},
+				-100, // before the TOC, which runs at 0
 			),
+			util.Prioritized(includeTransformer{}, -300),
+		}
+		mc := opts.Markdown(page)
+		exts, parserOpts, rendererOpts := mc.goldmarkOptions()
+		if *mc.Math {
+			exts = append(exts, MathExtension{Output: mc.MathOutput, Warn: page.warn})
+		}
+		if *mc.WikiLinks {
+			exts = append(exts, WikiLinks{Index: site.wiki, Warn: page.warn})
+		}
+		if opts.HeadingIDs() == headingIDsGitHub {
+			// IDs are set before the title heading might be stripped, so
+			// they match the page as GitHub renders it.
+			transformers = append(transformers, util.Prioritized(GitHubHeadingIDs{}, -200))
+		} else {
+			parserOpts = append(parserOpts, parser.WithAutoHeadingID())
+		}
+		parserOpts = append(parserOpts, parser.WithASTTransformers(transformers...))
+
+		hooks := &RenderHooks{
+			Templates: site.templates,
+			Page:      page,
+			Site:      site.config,
+		}
+		if *mc.Highlight {
+			hooks.Highlight = &Highlighter{Warn: page.warn}
+			site.highlight = true
+		}
+
+		md := goldmark.New(
+			goldmark.WithExtensions(append(exts, tocExt, hooks)...),
+			goldmark.WithRendererOptions(rendererOpts...),
+			goldmark.WithParserOptions(parserOpts...),
 		)
 
-		pc := parser.NewContext()
-		pc.Set(SourceFileKey, relPath)
+		body, restoreShortcodes, err := expandShortcodes(page, site, opts)
+		if err != nil {
+			return err
+		}
 
-		if err := md.Convert(page.Body, htmlBuf, parser.WithContext(pc)); err != nil {
-			return "", fmt.Errorf("failed rendering markdown: %w", err)
+		pc := parser.NewContext(parser.WithIDs(shortcodeIDs{parser.NewContext().IDs()}))
+		pc.Set(SourceFileKey, page.relPath)
+
+		src := []byte(body)
+		doc := md.Parser().Parse(text.NewReader(src), parser.WithContext(pc)).(*ast.Document)
+		if summaryDoc := splitSummary(doc, src, page.warn); summaryDoc != nil {
+			if err := md.Renderer().Render(htmlBuf, src, summaryDoc); err != nil {
+				return fmt.Errorf("failed rendering markdown: %w", err)
+			}
+			summaryHTML = restoreShortcodes(htmlBuf.String())
+			hasDivider = true
+			htmlBuf.Reset()
 		}
-		htmlStr = htmlBuf.String()
+		if err := md.Renderer().Render(htmlBuf, src, doc); err != nil {
+			return fmt.Errorf("failed rendering markdown: %w", err)
+		}
+		htmlStr = restoreShortcodes(htmlBuf.String())
 	} else {
 		htmlStr = string(page.Body)
+		if before, after, ok := strings.Cut(htmlStr, summaryDivider); ok {
+			summaryHTML, htmlStr, hasDivider = before, after, true
+		}
 	}
+	// Markdown links are handled while parsing, but links in raw HTML are
+	// only visible in the output.
+	summaryHTML = linkRewriter.RewriteHTML(page.relPath, summaryHTML)
+	htmlStr = summaryHTML + linkRewriter.RewriteHTML(page.relPath, htmlStr)
+
+	page.TOCItems = tocItems
+	page.TOC = template.HTML(GenerateTOC(tocItems))
+	page.setContent(htmlStr, summaryHTML, hasDivider, opts.SummaryLength())
+	return nil
+}
 
-	page.Params["TOCItems"] = tocItems
-	page.Params["TOC"] = template.HTML(GenerateTOC(tocItems))
-
+// renderPage executes the templates for a converted page.
+func renderPage(page *Page, tmpl *template.Template, opts *Options, site *site) (string, error) {
 	debugMap := map[string]any{
-		"Page":       page.Params,
-		"Site":       siteConfig,
+		"Page":       page,
+		"Site":       site.config,
+		"Menus":      site.menusFor(page),
 		"LiveReload": opts.LiveReload(),
 	}
 
 	tmplData := map[string]any{
-		"Content":  template.HTML(htmlStr),
+		"Content":  page.Content,
 		"DebugMap": debugMap,
 	}
 	maps.Copy(tmplData, debugMap)
 
+	layout := opts.BaseTemplate()
+	if page.Layout != "" {
+		layout = page.Layout
+	}
+
 	// Apply templates to both HTML and Markdown
 	var tmplBuf bytes.Buffer
-	err = tmpl.ExecuteTemplate(&tmplBuf, opts.BaseTemplate(), tmplData)
+	err := tmpl.ExecuteTemplate(&tmplBuf, layout, tmplData)
 	if err != nil {
-		return "", fmt.Errorf("failed rendering template: %w", err)
+		return "", fmt.Errorf("failed rendering template: %w", explainParams(err))
 	}
 
 	out := tmplBuf.String()
 
+	if opts.Strict() {
+		if err := strictErrors(page.warnings, out); err != nil {
+			return "", err
+		}
+	}
+
 	if opts.TidyHTML() {
 		out, err = htmltidy.NormalizeHTML(out)
 		if err != nil {
-			return "", fmt.Errorf("failed normalizing html %s: %w\n", path, err)
+			return "", fmt.Errorf("failed normalizing html %s: %w\n", page.path, err)
 		}
 	}
 
@@ -1,6 +1,7 @@
 package build
 
 import (
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/ianbruene/go-difflib/difflib"
 	"github.com/msolo/yugo/internal/htmltidy"
+	"github.com/msolo/yugo/internal/testutil"
 )
 
 func TestRenderFile(t *testing.T) {
@@ -69,6 +71,52 @@ Some ~~fine~~ content.`
 	}
 }
 
+func TestRenderFileOtherPageErrors(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{.Page.Title}}:{{.Content}}`,
+		"templates/bad.html":  `{{.Page.Nope}}`,
+		"content/page.md":     "---\n{\"Title\": \"Page\"}\n---\nText.\n",
+		"content/broken.md":   "---\n{\"Title\": 1}\n---\n",
+		"content/layout.md":   "---\n{\"Layout\": \"bad.html\"}\n---\n",
+	})
+
+	opts := &Options{&RawOptions{SiteDir: tmp}}
+	tmpl, err := loadTemplates(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := renderFile(filepath.Join(tmp, "content/page.md"), "page.md", tmpl, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "Page:<p>Text.</p>\n"; out != expected {
+		t.Fatalf("expected %q got %q", expected, out)
+	}
+
+	_, err = renderFile(filepath.Join(tmp, "content/broken.md"), "broken.md", tmpl, opts, nil)
+	if err == nil || !strings.HasPrefix(err.Error(), `broken.md: frontmatter key "Title"`) {
+		t.Fatalf("expected frontmatter error for broken.md, got %v", err)
+	}
+	_, err = renderFile(filepath.Join(tmp, "content/layout.md"), "layout.md", tmpl, opts, nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "layout.md: failed rendering template") {
+		t.Fatalf("expected template error for layout.md, got %v", err)
+	}
+}
+
+var writeFiles = testutil.WriteFiles
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
 // Normalize whitespace for comparison
 func normalize(s string) string {
 	out, _ := htmltidy.NormalizeHTML(s)
@@ -96,3 +144,304 @@ func strDiff(expected, out string) string {
 	}
 	return diff
 }
+
+func TestRenderContentStrict(t *testing.T) {
+	tmp := t.TempDir()
+
+	files := map[string]string{
+		"site.jsonr":          `{"Title": "Strict"}`,
+		"templates/base.html": `<html><body><a href="{{.Page.Params.Link}}">{{.Site.Missing}}</a>{{.Content}}</body></html>`,
+		"content/broken.md": `---
+{"Link": "/ok.html"}
+---
+[x](nosuch.md)`,
+		"content/unsafe.md": `---
+{"Link": "javascript:alert(1)"}
+---
+fine`,
+		"content/prose.md": `---
+{"Link": "/ok.html"}
+---
+Unsafe values show up as ZgotmplZ, as in ` + "`" + `<a href="ZgotmplZ">` + "`" + `.`,
+	}
+	writeFiles(t, tmp, files)
+
+	opts := &Options{&RawOptions{
+		SiteDir: tmp,
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatalf("non-strict build should succeed: %s", err)
+	}
+
+	opts.rawOptions.Strict = true
+	err := renderContent(opts)
+	buildErrs, ok := err.(BuildErrors)
+	if !ok {
+		t.Fatalf("expected BuildErrors, got: %v", err)
+	}
+	sources := []string{}
+	for _, pe := range buildErrs {
+		sources = append(sources, pe.Source)
+	}
+	if !slices.Equal([]string{"broken.md", "prose.md", "unsafe.md"}, sources) {
+		t.Fatalf("unexpected failing pages: %v\n%s", sources, err)
+	}
+	if !strings.Contains(err.Error(), "Missing") {
+		t.Fatalf("expected missing key error: %s", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmp, "templates/base.html"), []byte(`<a href="{{.Page.Params.Link}}">x</a>{{.Content}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = renderContent(opts)
+	for _, expected := range []string{"broken.md: broken link → nosuch.md", "unsafe.md: output line 1: unsafe value replaced with ZgotmplZ in href"} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q in: %v", expected, err)
+		}
+	}
+	if strings.Contains(err.Error(), "prose.md") {
+		t.Fatalf("text mentioning ZgotmplZ should not fail: %v", err)
+	}
+}
+
+func TestRenderContentPages(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{range .Site.Pages.ByDate.Reverse}}{{.Title}} {{.Date.Format "2006-01-02"}};{{end}}{{.Content}}`,
+		"templates/post.html": `post:{{.Page.Title}}`,
+		"content/a.md": `---
+{"Title": "A", "Date": "2024-01-02"}
+---
+[b](b.md)`,
+		"content/b.md": `---
+{"Title": "B", "Date": "2024-03-04", "Slug": "bee", "Layout": "post.html"}
+---
+b`,
+		"content/draft.md": `---
+{"Title": "Draft", "Date": "2025-01-01", "Draft": true}
+---
+draft`,
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir: tmp,
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	out := readFile(t, filepath.Join(tmp, "public/a.html"))
+	for _, expected := range []string{"B 2024-03-04;A 2024-01-02;", `href="bee.html"`} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %q in: %s", expected, out)
+		}
+	}
+	if out := readFile(t, filepath.Join(tmp, "public/bee.html")); out != "post:B" {
+		t.Fatalf("expected layout output, got: %s", out)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "public/draft.html")); err == nil {
+		t.Fatal("draft should not be rendered")
+	}
+
+	opts.rawOptions.BuildDrafts = true
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+	if out := readFile(t, filepath.Join(tmp, "public/a.html")); !strings.HasPrefix(out, "Draft 2025-01-01;") {
+		t.Fatalf("expected draft listed first: %s", out)
+	}
+}
+
+func TestLoadPagesOutputPaths(t *testing.T) {
+	for name, tc := range map[string]struct {
+		files map[string]string
+		err   string
+	}{
+		"slug separator": {
+			files: map[string]string{"content/a.md": "---\n{\"Slug\": \"x/y\"}\n---\n"},
+			err:   `a.md: frontmatter key "Slug": "x/y" must not contain a path separator or ".."`,
+		},
+		"slug backslash": {
+			files: map[string]string{"content/a.md": "---\n{\"Slug\": \"x\\\\y\"}\n---\n"},
+			err:   `a.md: frontmatter key "Slug": "x\\y" must not contain a path separator or ".."`,
+		},
+		"slug parent": {
+			files: map[string]string{"content/docs/a.md": "---\n{\"Slug\": \"..\"}\n---\n"},
+			err:   `docs/a.md: frontmatter key "Slug": ".." must not contain a path separator or ".."`,
+		},
+		"slug dot": {
+			files: map[string]string{"content/a.md": "---\n{\"Slug\": \".\"}\n---\n"},
+			err:   `a.md: frontmatter key "Slug": "." must not start with "."`,
+		},
+		"slug empty": {
+			files: map[string]string{"content/a.md": "---\n{\"Slug\": \" \"}\n---\n"},
+			err:   `a.md: frontmatter key "Slug": must not be empty`,
+		},
+		"slug collision": {
+			files: map[string]string{
+				"content/a.md": "---\n{\"Slug\": \"b\"}\n---\n",
+				"content/b.md": "b",
+			},
+			err: "b.md: output path b.html is already used by a.md",
+		},
+		"html collision": {
+			files: map[string]string{
+				"content/a.html": "<p>a</p>",
+				"content/a.md":   "a",
+			},
+			err: "a.md: output path a.html is already used by a.html",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tmp := t.TempDir()
+			writeFiles(t, tmp, tc.files)
+			opts := &Options{&RawOptions{SiteDir: tmp}}
+			_, err := loadPages(opts)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestRenderContentSummaries(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"yugo.jsonr":          `{"SummaryLength": 3}`,
+		"templates/base.html": `{{range .Site.Pages}}[{{.Summary}}|{{.WordCount}}|{{.ReadingTime}}]{{end}}`,
+		"content/a.md":        "# Alpha\n\nOne two three four.",
+		"content/b.md":        "Lead.\n\n<!--more-->\n\nMore.",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir: tmp,
+	}}
+	if err := opts.MergeConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[Alpha One two|5|1][<p>Lead.</p>\n|2|1]"
+	if out := readFile(t, filepath.Join(tmp, "public/a.html")); out != expected {
+		t.Fatalf("expected %q got %q", expected, out)
+	}
+}
+
+func TestRenderContentAncestors(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":                     `{}`,
+		"templates/base.html":            `{{template "_int/breadcrumbs.html" .}}{{with .Page.Parent}}parent:{{.Title}}/{{.LinkTitle}}{{end}}`,
+		"content/index.md":               "---\n{\"Title\": \"Start\"}\n---\n",
+		"content/docs/index.md":          "no title",
+		"content/docs/api/guide/page.md": "---\n{\"Title\": \"Page\"}\n---\n",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir: tmp,
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	out := normalize(readFile(t, filepath.Join(tmp, "public/docs/api/guide/page.html")))
+	expected := normalize(`<nav class="breadcrumbs" aria-label="Breadcrumb"><ol>
+<li><a href="/">Start</a></li>
+<li><a href="/docs/">docs</a></li>
+<li>api</li>
+<li>guide</li>
+<li aria-current="page">Page</li>
+</ol></nav>parent:/guide`)
+	if out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+
+	if out := readFile(t, filepath.Join(tmp, "public/docs/index.html")); !strings.HasSuffix(out, "parent:Start/Start") {
+		t.Fatalf("expected index page parent to be the root: %s", out)
+	}
+	if out := readFile(t, filepath.Join(tmp, "public/index.html")); out != "" {
+		t.Fatalf("expected no breadcrumbs on the root page: %q", out)
+	}
+}
+
+func TestRenderContentUntitledIndex(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":            `{}`,
+		"templates/base.html":   `title:{{.Page.Title}} link:{{.Page.LinkTitle}}`,
+		"content/index.md":      "",
+		"content/docs/index.md": "",
+		"content/docs/page.md":  "",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir: tmp,
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, expected := range map[string]string{
+		"index.html":      "title: link:Home",
+		"docs/index.html": "title: link:docs",
+		"docs/page.html":  "title: link:page",
+	} {
+		if out := readFile(t, filepath.Join(tmp, "public", file)); out != expected {
+			t.Errorf("%s: expected %q, got %q", file, expected, out)
+		}
+	}
+}
+
+func TestOverrideBuiltinTemplate(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":                      `{}`,
+		"templates/base.html":             `{{template "_int/breadcrumbs.html" .}}`,
+		"templates/_int/breadcrumbs.html": `custom`,
+		"content/index.md":                "",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir: tmp,
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+	if out := readFile(t, filepath.Join(tmp, "public/index.html")); out != "custom" {
+		t.Fatalf("expected overridden template: %q", out)
+	}
+}
+
+// siteTest is a site rendered with renderContent. Files are relative to the
+// site directory, and site.jsonr defaults to {}. SiteDir in opts is set to a
+// temporary directory. Output maps files under public to their expected
+// contents.
+type siteTest struct {
+	files  map[string]string
+	opts   RawOptions
+	output map[string]string
+}
+
+func (st siteTest) run(t *testing.T) {
+	t.Helper()
+	tmp := t.TempDir()
+	files := map[string]string{"site.jsonr": `{}`}
+	maps.Copy(files, st.files)
+	writeFiles(t, tmp, files)
+
+	ropts := st.opts
+	ropts.SiteDir = tmp
+	if err := renderContent(&Options{&ropts}); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range slices.Sorted(maps.Keys(st.output)) {
+		expected := st.output[file]
+		if out := readFile(t, filepath.Join(tmp, "public", file)); out != expected {
+			t.Errorf("%s: output diff:\n%s", file, strDiff(expected, out))
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package build
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Resource is a file in a page bundle, the directory of an index page. It is
+// copied to the output next to the page.
+type Resource struct {
+	Name      string // path relative to the bundle, unless set by frontmatter
+	Title     string // defaults to Name
+	Params    map[string]any
+	MediaType string // like "image/jpeg", or "" if unknown
+
+	URL          string
+	RelPermalink string
+	Permalink    string
+
+	path string // relative to the bundle, always slash-separated
+}
+
+// Resources are the files of a page bundle in path order.
+type Resources []*Resource
+
+// Match returns the resources whose Name matches a glob pattern, as in
+// path.Match. Matching ignores case.
+func (rs Resources) Match(pattern string) Resources {
+	out := Resources{}
+	for _, r := range rs {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(r.Name)); ok {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// GetMatch returns the first resource matching pattern, or nil.
+func (rs Resources) GetMatch(pattern string) *Resource {
+	if m := rs.Match(pattern); len(m) > 0 {
+		return m[0]
+	}
+	return nil
+}
+
+// ByType returns the resources with a media type such as "image/png", or
+// with a main type such as "image".
+func (rs Resources) ByType(mediaType string) Resources {
+	out := Resources{}
+	for _, r := range rs {
+		mainType, _, _ := strings.Cut(r.MediaType, "/")
+		if r.MediaType == mediaType || mainType == mediaType {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// resourceMeta is an entry of the Resources frontmatter key. The first entry
+// whose Src glob matches a resource sets each of its fields.
+//
+//	"Resources": [{"Src": "*.jpg", "Title": "Photo", "Params": {"Credit": "me"}}]
+type resourceMeta struct {
+	Src    string
+	Name   string
+	Title  string
+	Params map[string]any
+}
+
+func asResourceMetas(v any) ([]resourceMeta, error) {
+	metas := []resourceMeta{}
+	if err := remarshal(v, &metas); err != nil {
+		return nil, err
+	}
+	for _, m := range metas {
+		if _, err := path.Match(m.Src, ""); err != nil || m.Src == "" {
+			return nil, fmt.Errorf("invalid Src %q", m.Src)
+		}
+	}
+	return metas, nil
+}
+
+// mediaTypes maps lowercased file extensions to the MediaType of a resource.
+// It is fixed rather than read from the host's MIME database so that a site
+// builds the same everywhere.
+var mediaTypes = map[string]string{
+	".avif":  "image/avif",
+	".bmp":   "image/bmp",
+	".css":   "text/css",
+	".csv":   "text/csv",
+	".gif":   "image/gif",
+	".htm":   "text/html",
+	".html":  "text/html",
+	".ico":   "image/vnd.microsoft.icon",
+	".jpeg":  "image/jpeg",
+	".jpg":   "image/jpeg",
+	".js":    "text/javascript",
+	".json":  "application/json",
+	".m4a":   "audio/mp4",
+	".md":    "text/markdown",
+	".mp3":   "audio/mpeg",
+	".mp4":   "video/mp4",
+	".ogg":   "audio/ogg",
+	".otf":   "font/otf",
+	".pdf":   "application/pdf",
+	".png":   "image/png",
+	".svg":   "image/svg+xml",
+	".ttf":   "font/ttf",
+	".txt":   "text/plain",
+	".wasm":  "application/wasm",
+	".wav":   "audio/wav",
+	".webm":  "video/webm",
+	".webp":  "image/webp",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".xml":   "application/xml",
+	".zip":   "application/zip",
+}
+
+// loadResources collects the files of a bundle: every file under the page's
+// directory that is not itself content, stopping at subdirectories that are
+// bundles or sections of their own. The bundle of the site's root index page
+// is only its own directory, so it doesn't claim the files of every section
+// without an index.
+func (p *Page) loadResources(baseURL *url.URL) error {
+	bundleDir := filepath.Dir(p.path)
+	isRoot := filepath.Dir(p.relPath) == "."
+	urlDir := path.Dir("/" + filepath.ToSlash(p.outPath))
+
+	p.Resources = Resources{}
+	err := filepath.WalkDir(bundleDir, func(fpath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if fpath != bundleDir && (isRoot || hasIndex(fpath)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || shouldProcessFile(fpath) {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(bundleDir, fpath)
+		rel = filepath.ToSlash(rel)
+		r := &Resource{
+			Name:      rel,
+			Params:    map[string]any{},
+			MediaType: mediaTypes[strings.ToLower(path.Ext(rel))],
+			path:      rel,
+		}
+		r.URL, r.RelPermalink, r.Permalink = siteURLs(baseURL, path.Join(urlDir, rel))
+		p.Resources = append(p.Resources, r)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, r := range p.Resources {
+		name, title := "", ""
+		for _, m := range p.resourceMetas {
+			if ok, _ := path.Match(m.Src, r.path); !ok {
+				continue
+			}
+			name = cmp.Or(name, m.Name)
+			title = cmp.Or(title, m.Title)
+			for k, v := range m.Params {
+				if _, ok := r.Params[k]; !ok {
+					r.Params[k] = v
+				}
+			}
+		}
+		r.Name = cmp.Or(name, r.path)
+		r.Title = cmp.Or(title, r.Name)
+	}
+	return nil
+}
+
+// hasIndex reports whether a directory has an index page.
+func hasIndex(dir string) bool {
+	for _, name := range []string{"index.md", "index.html"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package build
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderContentBundle(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr": `{}`,
+		"templates/base.html": `{{range .Page.Resources.Match "*.jpg"}}[{{.Name}} {{.Title}} {{.URL}} {{.Permalink}} {{.MediaType}}{{with .Params.Credit}} {{.}}{{end}}]{{end}}` +
+			`{{with .Page.Resources.GetMatch "notes.txt"}}[{{.Title}}]{{end}}` +
+			`{{len (.Page.Resources.ByType "image")}}`,
+		"content/post/index.md": `---
+{
+  "Slug": "hello",
+  "Resources": [
+    {"Src": "b.jpg", "Name": "cover.jpg", "Title": "Cover"},
+    {"Src": "*.jpg", "Title": "Photo", "Params": {"Credit": "me"}},
+  ],
+}
+---
+`,
+		"content/post/a.jpg":           "a",
+		"content/post/b.jpg":           "b",
+		"content/post/notes.txt":       "notes",
+		"content/post/img/c.png":       "c",
+		"content/post/other.md":        "",
+		"content/post/nested/index.md": "",
+		"content/post/nested/d.jpg":    "d",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir: tmp,
+		BaseURL: "https://example.com/blog/",
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[a.jpg Photo /post/a.jpg https://example.com/blog/post/a.jpg image/jpeg me]" +
+		"[cover.jpg Cover /post/b.jpg https://example.com/blog/post/b.jpg image/jpeg me]" +
+		"[notes.txt]3"
+	if out := readFile(t, filepath.Join(tmp, "public/post/hello.html")); out != expected {
+		t.Fatalf("expected %q, got %q", expected, out)
+	}
+	if out := readFile(t, filepath.Join(tmp, "public/post/other.html")); out != "0" {
+		t.Fatalf("expected no resources outside index pages: %q", out)
+	}
+	if out := readFile(t, filepath.Join(tmp, "public/post/nested/index.html")); out != "[d.jpg d.jpg /post/nested/d.jpg https://example.com/blog/post/nested/d.jpg image/jpeg]1" {
+		t.Fatalf("unexpected nested bundle resources: %q", out)
+	}
+}
+
+func TestRenderContentRootBundle(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{range .Page.Resources}}[{{.Name}} {{.MediaType}}]{{end}}`,
+		"content/index.md":    "",
+		"content/logo.PNG":    "logo",
+		"content/data.xyz":    "data",
+		"content/docs/a.png":  "a",
+		"content/docs/a.md":   "",
+	})
+
+	if err := renderContent(&Options{&RawOptions{SiteDir: tmp}}); err != nil {
+		t.Fatal(err)
+	}
+	if out := readFile(t, filepath.Join(tmp, "public/index.html")); out != "[data.xyz ][logo.PNG image/png]" {
+		t.Fatalf("unexpected root bundle resources: %q", out)
+	}
+}
+
+func TestParsePageResourcesError(t *testing.T) {
+	_, err := ParsePage([]byte("---\n{\"Resources\": [{\"Src\": \"[\"}]}\n---\n"), nil)
+	if err == nil || err.Error() != `frontmatter key "Resources": invalid Src "["` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
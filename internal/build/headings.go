@@ -0,0 +1,77 @@
+package build
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// Heading ID styles for the HeadingIDs option. The goldmark style is
+// goldmark's own auto heading IDs.
+const (
+	headingIDsGoldmark = "goldmark"
+	headingIDsGitHub   = "github"
+)
+
+// GitHubSlug converts heading text to an anchor the way GitHub does: the text
+// is lowercased, everything except letters, marks, numbers, underscores,
+// hyphens and spaces is removed, and spaces become hyphens.
+func GitHubSlug(s string) string {
+	b := strings.Builder{}
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case r == '-', unicode.IsLetter(r), unicode.IsMark(r), unicode.IsNumber(r), unicode.Is(unicode.Pc, r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// GitHubHeadingIDs sets the id of every heading from its rendered text with
+// GitHub's rules. Repeated slugs get -1, -2 and so on, skipping any that are
+// already taken. Headings with an explicit id keep it, and no other heading
+// gets it. It must run before the TOC is collected.
+type GitHubHeadingIDs struct{}
+
+func (GitHubHeadingIDs) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	seen := map[string]int{}
+	headings := []*ast.Heading{}
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		if id, ok := heading.AttributeString("id"); ok {
+			// Set with the attribute syntax.
+			seen[string(id.([]byte))] = 0
+		} else {
+			headings = append(headings, heading)
+		}
+		return ast.WalkSkipChildren, nil
+	})
+
+	for _, heading := range headings {
+		slug := GitHubSlug(strings.TrimSpace(nodeText(heading, source)))
+		if slug == "" {
+			// GitHub allows this, but an empty id can't be linked to.
+			slug = "heading"
+		}
+		id := slug
+		for {
+			if _, taken := seen[id]; !taken {
+				break
+			}
+			seen[slug]++
+			id = fmt.Sprintf("%s-%d", slug, seen[slug])
+		}
+		seen[id] = 0
+		heading.SetAttributeString("id", []byte(id))
+	}
+}
@@ -0,0 +1,97 @@
+package build
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/util"
+)
+
+func TestGitHubSlug(t *testing.T) {
+	for in, expected := range map[string]string{
+		"Hello World":                "hello-world",
+		"  Leading and trailing ":    "--leading-and-trailing-",
+		"What's new in v1.2?":        "whats-new-in-v12",
+		"snake_case & kebab-case":    "snake_case--kebab-case",
+		"Ünïcödé Straße":             "ünïcödé-straße",
+		"日本語 見出し":                    "日本語-見出し",
+		"emoji 🎉 party":              "emoji--party",
+		"C++ / C# (and F#)":          "c--c-and-f",
+		"--already-a-slug--":         "--already-a-slug--",
+		"Tabs\tare\tremoved":         "tabsareremoved",
+		"<html> & \"quotes\"":        "html--quotes",
+		"1. Numbered":                "1-numbered",
+		"":                           "",
+		"Mixed CASE with ⅱ numerals": "mixed-case-with-ⅱ-numerals",
+	} {
+		if out := GitHubSlug(in); out != expected {
+			t.Errorf("GitHubSlug(%q): expected %q, got %q", in, expected, out)
+		}
+	}
+}
+
+func TestRenderContentGitHubHeadingIDs(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{range .Page.TOCItems}}{{.ID}} {{end}}|{{.Content}}`,
+		"content/a.md":        "# Intro\n\n## Set *up*\n\n## Set up\n\n## Set up-1\n\n## Set up\n\n## What's `new`?\n\n## !!!\n\n<a name=\"raw\"></a>\n",
+		"content/b.md":        "[ok](a.md#set-up-2) [no](a.md#set-up-3) [raw](a.md#raw) [self](#x)\n",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir:    tmp,
+		HeadingIDs: "github",
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	out := readFile(t, filepath.Join(tmp, "public/a.html"))
+	toc, _, _ := strings.Cut(out, "|")
+	expected := "intro set-up set-up-1 set-up-1-1 set-up-2 whats-new heading "
+	if toc != expected {
+		t.Fatalf("expected TOC ids %q, got %q", expected, toc)
+	}
+	if !strings.Contains(out, `<h2 id="set-up-1-1">Set up-1</h2>`) {
+		t.Fatalf("expected heading ids in content: %s", out)
+	}
+
+	// Anchors into a.md are checked against its IDs.
+	opts.rawOptions.Strict = true
+	err := renderContent(opts)
+	buildErrs := BuildErrors{}
+	if !errors.As(err, &buildErrs) || len(buildErrs) != 1 || buildErrs[0].Source != "b.md" {
+		t.Fatalf("expected one failing page, got %v", err)
+	}
+	if msg := buildErrs[0].Error(); !strings.Contains(msg, "broken anchor → a.md#set-up-3") || strings.Contains(msg, "#raw") {
+		t.Fatalf("unexpected error: %s", msg)
+	}
+}
+
+func TestGitHubHeadingIDs(t *testing.T) {
+	md := goldmark.New(goldmark.WithParserOptions(
+		parser.WithAttribute(),
+		parser.WithASTTransformers(util.Prioritized(GitHubHeadingIDs{}, 0)),
+	))
+	for in, expected := range map[string]string{
+		"# !!!\n# ???\n":           `<h1 id="heading">!!!</h1><h1 id="heading-1">???</h1>`,
+		"# Foo\n# Bar {#foo}\n":    `<h1 id="foo-1">Foo</h1><h1 id="foo">Bar</h1>`,
+		"# A\n# A\n# Set {#a-1}\n": `<h1 id="a">A</h1><h1 id="a-2">A</h1><h1 id="a-1">Set</h1>`,
+		"# ?\n# x {#heading}\n":    `<h1 id="heading-1">?</h1><h1 id="heading">x</h1>`,
+		"# Same\n\n> # Same\n":     `<h1 id="same">Same</h1><blockquote><h1 id="same-1">Same</h1></blockquote>`,
+	} {
+		buf := &bytes.Buffer{}
+		if err := md.Convert([]byte(in), buf); err != nil {
+			t.Fatal(err)
+		}
+		if out := strings.ReplaceAll(buf.String(), "\n", ""); out != expected {
+			t.Errorf("%q: expected %s, got %s", in, expected, out)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package build
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+const (
+	defaultHighlightStyle = "github"
+	// highlightCSSFile is written to the output directory when highlighting
+	// is enabled, unless the static directory has its own.
+	highlightCSSFile = "css/highlight.css"
+)
+
+func init() {
+	// JSONR is JSON with /* */ comments as well as // comments.
+	rules := lexers.Get("json").(*chroma.RegexLexer).MustRules().Clone()
+	rules["comment"] = append([]chroma.Rule{
+		{Pattern: `/\*[\s\S]*?\*/`, Type: chroma.CommentMultiline},
+	}, rules["comment"]...)
+	lexers.Register(chroma.MustNewLexer(
+		&chroma.Config{
+			Name:      "JSONR",
+			Aliases:   []string{"jsonr"},
+			Filenames: []string{"*.jsonr"},
+		},
+		func() chroma.Rules { return rules },
+	))
+}
+
+// Highlighter renders fenced code blocks in a known language as spans with
+// chroma's CSS classes. The info string can have attributes:
+//
+//	```go {linenos=true hl_lines="3-5 8" linenostart=10}
+//
+// linenos is true, false, "inline" or "table". hl_lines counts from the
+// first line of the block, whatever linenostart is.
+type Highlighter struct {
+	Warn func(msg string)
+}
+
+// codeBlock wraps the renderer used for blocks it can't highlight.
+func (hl *Highlighter) codeBlock(fallback renderer.NodeRendererFunc) renderer.NodeRendererFunc {
+	return func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		block := n.(*ast.FencedCodeBlock)
+		lexer := lexers.Get(fenceLanguage(block, source))
+		if lexer == nil {
+			return fallback(w, source, n, entering)
+		}
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		code := strings.Builder{}
+		for i := 0; i < block.Lines().Len(); i++ {
+			line := block.Lines().At(i)
+			code.Write(line.Value(source))
+		}
+		tokens, err := chroma.Coalesce(lexer).Tokenise(nil, code.String())
+		if err != nil {
+			return ast.WalkStop, err
+		}
+
+		formatter := chromahtml.New(append([]chromahtml.Option{
+			chromahtml.WithClasses(true),
+			// Keep every class so any style's stylesheet applies.
+			chromahtml.WithAllClasses(true),
+		}, hl.fenceOptions(block, source)...)...)
+		_, _ = w.WriteString(`<div class="highlight">`)
+		if err := formatter.Format(w, styles.Fallback, tokens); err != nil {
+			return ast.WalkStop, err
+		}
+		_, _ = w.WriteString("</div>\n")
+		return ast.WalkSkipChildren, nil
+	}
+}
+
+// fenceLanguage returns the language of a fenced code block. goldmark
+// takes everything up to the first space, so attributes written without
+// one, like go{linenos=true}, are cut off here.
+func fenceLanguage(block *ast.FencedCodeBlock, source []byte) string {
+	lang, _, _ := bytes.Cut(block.Language(source), []byte("{"))
+	return string(lang)
+}
+
+// fenceOptions reads the attributes in braces after the language.
+func (hl *Highlighter) fenceOptions(block *ast.FencedCodeBlock, source []byte) []chromahtml.Option {
+	if block.Info == nil {
+		return nil
+	}
+	info := block.Info.Segment.Value(source)
+	i := bytes.IndexByte(info, '{')
+	if i < 0 {
+		return nil
+	}
+	attrs, ok := parser.ParseAttributes(text.NewReader(info[i:]))
+	if !ok {
+		hl.Warn(fmt.Sprintf("invalid code block attributes %s", info[i:]))
+		return nil
+	}
+
+	opts := []chromahtml.Option{}
+	base := 1
+	ranges := [][2]int{}
+	for _, a := range attrs {
+		value := attrString(a.Value)
+		switch name := string(a.Name); name {
+		case "linenos":
+			switch value {
+			case "true", "inline":
+				opts = append(opts, chromahtml.WithLineNumbers(true))
+			case "table":
+				opts = append(opts, chromahtml.WithLineNumbers(true), chromahtml.LineNumbersInTable(true))
+			case "false":
+			default:
+				hl.Warn(fmt.Sprintf("invalid linenos %q, expected true, false, inline or table", value))
+			}
+		case "linenostart":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				hl.Warn(fmt.Sprintf("invalid linenostart %q", value))
+				continue
+			}
+			base = n
+			opts = append(opts, chromahtml.BaseLineNumber(n))
+		case "hl_lines":
+			r, err := parseLineRanges(value)
+			if err != nil {
+				hl.Warn(fmt.Sprintf("invalid hl_lines %q: %s", value, err))
+				continue
+			}
+			ranges = r
+		}
+	}
+	// chroma compares ranges with the displayed line numbers.
+	for i := range ranges {
+		ranges[i][0] += base - 1
+		ranges[i][1] += base - 1
+	}
+	if len(ranges) > 0 {
+		opts = append(opts, chromahtml.HighlightLines(ranges))
+	}
+	return opts
+}
+
+func attrString(v any) string {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// parseLineRanges parses line numbers and ranges separated by spaces or
+// commas, like "1 3-5".
+func parseLineRanges(s string) ([][2]int, error) {
+	ranges := [][2]int{}
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+		from, to, isRange := strings.Cut(field, "-")
+		start, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("bad line %q", field)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(to); err != nil || end < start {
+				return nil, fmt.Errorf("bad range %q", field)
+			}
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return ranges, nil
+}
+
+// WriteHighlightCSS writes the stylesheet for highlighted code in one of
+// chroma's styles.
+func WriteHighlightCSS(w io.Writer, style string) error {
+	s, ok := styles.Registry[style]
+	if !ok {
+		return fmt.Errorf("unknown highlight style %q", style)
+	}
+	return chromahtml.New(chromahtml.WithClasses(true), chromahtml.WithAllClasses(true)).WriteCSS(w, s)
+}
@@ -0,0 +1,126 @@
+package build
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+)
+
+func TestRenderContentHighlight(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{.Content}}`,
+		"templates/_render/codeblock-mermaid.html": `<div class="mermaid">{{.Code}}</div>` + "\n",
+		"content/page.md": "```go {linenos=true hl_lines=\"2\" linenostart=10}\npackage main\n// hi\n```\n\n" +
+			"```jsonr\n{/* c */ \"a\": 1}\n```\n\n" +
+			"```nope\nx < y\n```\n\n" +
+			"```mermaid\nA-->B\n```\n",
+		"content/plain.md": "---\n{\"Markdown\": {\"Highlight\": false}}\n---\n```go\nx := 1\n```\n",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir:  tmp,
+		Markdown: MarkdownConfig{Highlight: ptr(true)},
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<div class="highlight"><pre class="chroma"><code><span class="line"><span class="ln">10</span><span class="cl"><span class="kn">package</span><span class="w"> </span><span class="nx">main</span><span class="w">
+</span></span></span><span class="line hl"><span class="ln">11</span><span class="cl"><span class="c1">// hi</span><span class="w">
+</span></span></span></code></pre></div>
+<div class="highlight"><pre class="chroma"><code><span class="line"><span class="cl"><span class="p">{</span><span class="cm">/* c */</span> <span class="nt">&#34;a&#34;</span><span class="p">:</span> <span class="mi">1</span><span class="p">}</span>
+</span></span></code></pre></div>
+<pre><code class="language-nope">x &lt; y
+</code></pre>
+<div class="mermaid">A--&gt;B
+</div>
+`
+	if out := readFile(t, filepath.Join(tmp, "public/page.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+
+	expected = "<pre><code class=\"language-go\">x := 1\n</code></pre>\n"
+	if out := readFile(t, filepath.Join(tmp, "public/plain.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+
+	if css := readFile(t, filepath.Join(tmp, "public", highlightCSSFile)); !strings.Contains(css, ".chroma .kn {") {
+		t.Fatalf("stylesheet has no keyword rule:\n%s", css)
+	}
+}
+
+func TestRenderContentHighlightStaticCSS(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":               `{}`,
+		"templates/base.html":      `{{.Content}}`,
+		"static/css/highlight.css": `.chroma {}`,
+		"content/page.md":          "---\n{\"Markdown\": {\"Highlight\": true}}\n---\n```sh\nls\n```\n",
+	})
+
+	opts := &Options{&RawOptions{SiteDir: tmp}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "public", highlightCSSFile)); !os.IsNotExist(err) {
+		t.Fatalf("expected the static stylesheet to be used, got %v", err)
+	}
+}
+
+func TestHighlightWarnings(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{.Content}}`,
+		"content/page.md":     "```go {linenos=maybe hl_lines=\"5-3\"}\nx := 1\n```\n",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir:  tmp,
+		Strict:   true,
+		Markdown: MarkdownConfig{Highlight: ptr(true)},
+	}}
+	err := renderContent(opts)
+	if err == nil {
+		t.Fatal("expected warnings to fail a strict build")
+	}
+	for _, want := range []string{`invalid linenos "maybe"`, `invalid hl_lines "5-3": bad range "5-3"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestParseLineRanges(t *testing.T) {
+	ranges, err := parseLineRanges("1 3-5,8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := [][2]int{{1, 1}, {3, 5}, {8, 8}}; !reflect.DeepEqual(ranges, expected) {
+		t.Fatalf("expected %v, got %v", expected, ranges)
+	}
+	if _, err := parseLineRanges("a-2"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestHighlightAttributesWithoutSpace(t *testing.T) {
+	warnings := []string{}
+	hooks := &RenderHooks{Highlight: &Highlighter{Warn: func(msg string) { warnings = append(warnings, msg) }}}
+	md := goldmark.New(goldmark.WithExtensions(hooks))
+	buf := &bytes.Buffer{}
+	if err := md.Convert([]byte("```go{linenos=true}\nx := 1\n```\n"), buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `<div class="highlight"><pre class="chroma"><code><span class="line"><span class="ln">1</span><span class="cl"><span class="nx">x</span>`
+	if out := buf.String(); !strings.HasPrefix(out, expected) || len(warnings) > 0 {
+		t.Fatalf("expected highlighted code with line numbers, got %s warnings: %q", out, warnings)
+	}
+}
@@ -0,0 +1,216 @@
+package build
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// renderHookDir holds templates that replace goldmark's renderers for
+// links, images, headings and fenced code blocks.
+const renderHookDir = "_render/"
+
+// RenderHooks renders Markdown nodes with the templates in _render/ when
+// they exist. Nodes without a template are rendered by goldmark as usual.
+//
+// Every hook gets .Page, .Site and .Attributes. Links get .Destination,
+// .Title, .Text and .PlainText; images get .Destination, .Title and .Text
+// (the alt text); headings get .Level, .ID, .Text and .PlainText; alerts
+// get .Type, like "note", .Title and .Text, the rendered blocks inside; code
+// blocks get .Lang, .Info and .Code. A code block uses
+// codeblock-<lang>.html if it exists, otherwise codeblock.html.
+type RenderHooks struct {
+	Templates *template.Template
+	Page      *Page
+	Site      map[string]any
+	// Renders code blocks without a template, if set.
+	Highlight *Highlighter
+
+	// Renders the children of a node, for .Text. Set by Extend.
+	md goldmark.Markdown
+	// Renderer options, like XHTML, and goldmark's own code block renderer
+	// configured with them, for code blocks without a template.
+	options          map[renderer.OptionName]any
+	defaultCodeBlock renderer.NodeRendererFunc
+}
+
+// SetOption receives the renderer options before RegisterFuncs is called.
+func (h *RenderHooks) SetOption(name renderer.OptionName, value any) {
+	if h.options == nil {
+		h.options = map[renderer.OptionName]any{}
+	}
+	h.options[name] = value
+}
+
+// funcCapture records the functions a node renderer registers.
+type funcCapture map[ast.NodeKind]renderer.NodeRendererFunc
+
+func (f funcCapture) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	f[kind] = fn
+}
+
+func (h *RenderHooks) Extend(m goldmark.Markdown) {
+	h.md = m
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		// Ahead of goldmark's html renderer at 1000 and extensions at 500.
+		util.Prioritized(h, 100),
+	))
+}
+
+func (h *RenderHooks) lookup(name string) *template.Template {
+	if h.Templates == nil {
+		return nil
+	}
+	return h.Templates.Lookup(renderHookDir + name)
+}
+
+func (h *RenderHooks) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	for kind, name := range map[ast.NodeKind]string{
+		ast.KindLink:    "link.html",
+		ast.KindImage:   "image.html",
+		ast.KindHeading: "heading.html",
+		KindAlert:       "alert.html",
+	} {
+		if tmpl := h.lookup(name); tmpl != nil {
+			reg.Register(kind, h.hook(tmpl))
+		}
+	}
+	// Language specific templates can exist without codeblock.html, so
+	// always take over code blocks and fall back to the highlighter or
+	// goldmark's renderer.
+	if h.Highlight != nil || (h.Templates != nil && h.hasCodeBlockHooks()) {
+		fallback := html.NewRenderer()
+		for name, value := range h.options {
+			fallback.(renderer.SetOptioner).SetOption(name, value)
+		}
+		funcs := funcCapture{}
+		fallback.RegisterFuncs(funcs)
+		h.defaultCodeBlock = funcs[ast.KindFencedCodeBlock]
+		if h.Highlight != nil {
+			h.defaultCodeBlock = h.Highlight.codeBlock(h.defaultCodeBlock)
+		}
+		reg.Register(ast.KindFencedCodeBlock, h.renderCodeBlock)
+	}
+}
+
+func (h *RenderHooks) hasCodeBlockHooks() bool {
+	for _, t := range h.Templates.Templates() {
+		name := t.Name()
+		if name == renderHookDir+"codeblock.html" || strings.HasPrefix(name, renderHookDir+"codeblock-") {
+			return true
+		}
+	}
+	return false
+}
+
+// hook renders a link, image or heading with tmpl.
+func (h *RenderHooks) hook(tmpl *template.Template) renderer.NodeRendererFunc {
+	return func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		data := h.data(n)
+		switch n := n.(type) {
+		case *ast.Link:
+			data["Destination"] = string(n.Destination)
+			data["Title"] = string(n.Title)
+		case *ast.Image:
+			data["Destination"] = string(n.Destination)
+			data["Title"] = string(n.Title)
+		case *ast.Heading:
+			data["Level"] = n.Level
+			id, _ := n.AttributeString("id")
+			idBytes, _ := id.([]byte)
+			data["ID"] = string(idBytes)
+		case *Alert:
+			data["Type"] = n.AlertType
+			data["Title"] = n.Title()
+		}
+
+		if _, ok := n.(*ast.Image); ok {
+			data["Text"] = nodeText(n, source)
+		} else {
+			inner, err := h.renderChildren(source, n)
+			if err != nil {
+				return ast.WalkStop, err
+			}
+			data["Text"] = template.HTML(inner)
+			data["PlainText"] = nodeText(n, source)
+		}
+
+		if err := tmpl.Execute(w, data); err != nil {
+			return ast.WalkStop, err
+		}
+		return ast.WalkSkipChildren, nil
+	}
+}
+
+func (h *RenderHooks) renderCodeBlock(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	block := n.(*ast.FencedCodeBlock)
+	lang := fenceLanguage(block, source)
+
+	tmpl := h.lookup("codeblock-" + lang + ".html")
+	if lang == "" || tmpl == nil {
+		tmpl = h.lookup("codeblock.html")
+	}
+	if tmpl == nil {
+		return h.defaultCodeBlock(w, source, n, entering)
+	}
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	info := ""
+	if block.Info != nil {
+		info = string(block.Info.Segment.Value(source))
+	}
+	code := strings.Builder{}
+	for i := 0; i < block.Lines().Len(); i++ {
+		line := block.Lines().At(i)
+		code.Write(line.Value(source))
+	}
+
+	data := h.data(n)
+	data["Lang"] = lang
+	data["Info"] = info
+	data["Code"] = code.String()
+	if err := tmpl.Execute(w, data); err != nil {
+		return ast.WalkStop, err
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// data is the template data every hook shares.
+func (h *RenderHooks) data(n ast.Node) map[string]any {
+	attrs := map[string]any{}
+	for _, a := range n.Attributes() {
+		switch v := a.Value.(type) {
+		case []byte:
+			attrs[string(a.Name)] = string(v)
+		default:
+			attrs[string(a.Name)] = v
+		}
+	}
+	return map[string]any{
+		"Page":       h.Page,
+		"Site":       h.Site,
+		"Attributes": attrs,
+	}
+}
+
+// renderChildren renders the inline content of a node to HTML.
+func (h *RenderHooks) renderChildren(source []byte, n ast.Node) (string, error) {
+	b := &bytes.Buffer{}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if err := h.md.Renderer().Render(b, source, c); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
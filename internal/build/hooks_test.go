@@ -0,0 +1,46 @@
+package build
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderContentRenderHooks(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":                               `{"Title": "Site"}`,
+		"templates/base.html":                      `{{.Content}}`,
+		"templates/_render/link.html":              `<a href="{{.Destination}}"{{with .Title}} title="{{.}}"{{end}}>{{.Text}}</a>{{if .Title}} ↗{{end}}`,
+		"templates/_render/image.html":             `<figure><img src="{{.Destination}}" alt="{{.Text}}"><figcaption>{{.Title}}</figcaption></figure>`,
+		"templates/_render/heading.html":           `<h{{.Level}} id="{{.ID}}">{{.Text}} <a href="#{{.ID}}">#</a></h{{.Level}}>` + "\n",
+		"templates/_render/codeblock-mermaid.html": `<div class="mermaid" data-info="{{.Info}}">{{.Code}}</div>` + "\n",
+		"content/other.md":                         "",
+		"content/page.md": "## Some *title*\n\n" +
+			"[ext](https://example.com \"T\") and [local **bold**](other.md) on {{.Page.Title}}\n\n" +
+			"![Alt text](pic.jpg \"Caption\")\n\n" +
+			"```mermaid {x=1}\ngraph TD; A-->B\n```\n\n" +
+			"```go\nfmt.Println(\"<hi>\")\n```\n",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir: tmp,
+		Markdown: MarkdownConfig{
+			XHTML: ptr(true),
+		},
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<h2 id="some-title">Some <em>title</em> <a href="#some-title">#</a></h2>
+<p><a href="https://example.com" title="T">ext</a> ↗ and <a href="other.html">local <strong>bold</strong></a> on {{.Page.Title}}</p>
+<p><figure><img src="pic.jpg" alt="Alt text"><figcaption>Caption</figcaption></figure></p>
+<div class="mermaid" data-info="mermaid {x=1}">graph TD; A--&gt;B
+</div>
+<pre><code class="language-go">fmt.Println(&quot;&lt;hi&gt;&quot;)
+</code></pre>
+`
+	if out := readFile(t, filepath.Join(tmp, "public/page.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+}
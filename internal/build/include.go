@@ -0,0 +1,195 @@
+package build
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// includeShortcode is the built-in shortcode that splices another Markdown
+// file into a page: {{% include "../shared/install.md" %}}. The path is
+// resolved like a link, relative to the file containing the shortcode, or
+// to ContentDir if it starts with /. It must stay within RepoDir.
+const includeShortcode = "include"
+
+// includeMarker brackets included Markdown, so that includeTransformer can
+// tell which file each link came from. The markers are HTML comments on
+// lines of their own, which parse as HTML blocks wherever the include is.
+// Each follows a blank line, so that it can't become part of a raw HTML
+// block that runs up to it.
+var includeMarker = regexp.MustCompile(`^<!-- yugo:include (.*) -->$`)
+
+const includeEndMarker = "<!-- yugo:include -->"
+
+// include returns the body of the file named by an include shortcode, with
+// its own shortcodes expanded, as Markdown indented like the shortcode.
+func (e *shortcodeExpander) include(tag *shortcodeTag, inner string) (string, error) {
+	indent, err := e.blockIndent(tag)
+	if err != nil {
+		return "", err
+	}
+	if len(tag.args) != 1 || inner != "" {
+		return "", e.errorf(tag.start, "shortcode %q takes one path and no content", tag.name)
+	}
+
+	file := tag.args[0]
+	if filepath.IsAbs(file) {
+		file = filepath.Clean(strings.TrimPrefix(file, "/"))
+	} else {
+		file = filepath.Join(filepath.Dir(e.file), file)
+	}
+	path := filepath.Join(e.opts.ContentDir(), file)
+	if rel, err := absRel(e.opts.RepoDir(), path); err != nil || !filepath.IsLocal(rel) {
+		return "", e.errorf(tag.start, "include outside the repository: %s", tag.args[0])
+	}
+	abs := absPath(path)
+	if i := slices.Index(e.includes, abs); i >= 0 {
+		cycle := []string{}
+		for _, p := range append(e.includes[i:], abs) {
+			if rel, err := absRel(e.opts.ContentDir(), p); err == nil {
+				p = rel
+			}
+			cycle = append(cycle, filepath.ToSlash(p))
+		}
+		return "", e.errorf(tag.start, "include cycle: %s", strings.Join(cycle, " → "))
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", e.errorf(tag.start, "include: %s", err)
+	}
+	// Frontmatter is allowed so that included files can be previewed on
+	// their own, but it is ignored.
+	included, err := ParsePage(src, e.opts.Location())
+	if err != nil {
+		return "", e.errorf(tag.start, "include %s: %s", tag.args[0], err)
+	}
+	e.page.includes = append(e.page.includes, abs)
+
+	child := *e
+	child.body = string(included.Body)
+	child.file = file
+	child.bodyLine = included.bodyLine
+	child.includes = append(slices.Clip(e.includes), abs)
+	md, err := child.expand(0, len(child.body))
+	if err != nil {
+		return "", fmt.Errorf("line %d: include %s: %w", e.line(tag.start), tag.args[0], err)
+	}
+
+	return fmt.Sprintf("\n%s<!-- yugo:include %s -->\n", indent, filepath.ToSlash(file)) +
+		indent + indentLines(md, indent) + "\n" + indent + includeEndMarker, nil
+}
+
+// blockIndent checks that a built-in shortcode producing Markdown blocks is
+// written {{% %}} on a line of its own, and returns the whitespace before it.
+func (e *shortcodeExpander) blockIndent(tag *shortcodeTag) (string, error) {
+	if !tag.markdown {
+		return "", e.errorf(tag.start, "shortcode %q must be written {{%% %s %%}}", tag.name, tag.name)
+	}
+	lineStart := strings.LastIndexByte(e.body[:tag.start], '\n') + 1
+	indent := e.body[lineStart:tag.start]
+	lineEnd := strings.IndexByte(e.body[tag.end:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(e.body) - tag.end
+	}
+	if strings.TrimSpace(indent) != "" || strings.TrimSpace(e.body[tag.end:tag.end+lineEnd]) != "" {
+		return "", e.errorf(tag.start, "shortcode %q must be on a line of its own", tag.name)
+	}
+	return indent, nil
+}
+
+// indentLines ends s with a newline and indents every line but the first,
+// which follows the existing indent. Blank lines are left empty.
+func indentLines(s, indent string) string {
+	out := strings.Builder{}
+	for i, line := range strings.SplitAfter(strings.TrimRight(s, "\n")+"\n", "\n") {
+		if i > 0 && strings.TrimSpace(line) != "" {
+			out.WriteString(indent)
+		}
+		out.WriteString(line)
+	}
+	return out.String()
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// IncludedFilesKey holds a map from the links and images that came from
+// included files to the file, relative to ContentDir.
+var IncludedFilesKey = parser.NewContextKey()
+
+// includeTransformer removes the markers around included Markdown and
+// records which links and images they enclosed in IncludedFilesKey.
+type includeTransformer struct{}
+
+func (t includeTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	wikiLinks, _ := pc.Get(wikiLinksKey).(map[ast.Node]bool)
+	files := map[ast.Node]string{}
+	markers := []ast.Node{}
+	stack := []string{}
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.HTMLBlock:
+			if n.Lines().Len() != 1 {
+				break
+			}
+			seg := n.Lines().At(0)
+			line := strings.TrimSpace(string(seg.Value(source)))
+			if line == includeEndMarker && len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+				markers = append(markers, n)
+			} else if m := includeMarker.FindStringSubmatch(line); m != nil {
+				stack = append(stack, filepath.FromSlash(m[1]))
+				markers = append(markers, n)
+			}
+		case *ast.Link, *ast.Image:
+			// Wiki links are resolved from the page already.
+			if len(stack) > 0 && !wikiLinks[n] {
+				files[n] = stack[len(stack)-1]
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, n := range markers {
+		n.Parent().RemoveChild(n.Parent(), n)
+	}
+	pc.Set(IncludedFilesKey, files)
+}
+
+// rebaseRef rewrites a relative reference written in fromFile so that it
+// points at the same place from toFile. Both files are relative to the same
+// directory. Anything that isn't a relative path is returned as is.
+func rebaseRef(dest, fromFile, toFile string) string {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || strings.HasPrefix(u.Path, "/") {
+		return dest
+	}
+	base, anchor := splitAnchor(dest)
+	rel, err := filepath.Rel(filepath.Dir(toFile), filepath.Join(filepath.Dir(fromFile), base))
+	if err != nil {
+		return dest
+	}
+	rel = filepath.ToSlash(rel)
+	if strings.HasSuffix(base, "/") && rel != "." {
+		rel += "/"
+	} else if rel == "." {
+		rel = "./"
+	}
+	return withAnchor(rel, anchor)
+}
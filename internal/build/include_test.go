@@ -0,0 +1,173 @@
+package build
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRenderContentInclude(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{.Content}}back:{{range .Page.Backlinks}}[{{.Page.Title}}]{{end}}`,
+		"content/docs/guide.md": "---\n{\"Title\": \"Guide\"}\n---\n# Guide\n\n" +
+			"{{% include \"../shared/install.md\" %}}\n\n" +
+			"- Item:\n\n  {{% include \"/shared/note.md\" %}}\n",
+		"content/shared/install.md": "---\n{\"Title\": \"Install\"}\n---\n" +
+			"Run the [setup](setup.md#run), see ![logo](img/logo.png), [the site](https://example.com) and [the top](#guide).\n\n" +
+			"{{% include \"note.md\" %}}\n",
+		"content/shared/note.md":  "---\n{\"Title\": \"Note\"}\n---\nA *note* about [setup](setup.md).\n",
+		"content/shared/setup.md": "---\n{\"Title\": \"Setup\"}\n---\n# Run\n",
+	})
+
+	opts := &Options{&RawOptions{SiteDir: tmp}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<h1 id="guide">Guide</h1>
+<p>Run the <a href="../shared/setup.html#run">setup</a>, see <img src="../shared/img/logo.png" alt="logo">, <a href="https://example.com">the site</a> and <a href="#guide">the top</a>.</p>
+<p>A <em>note</em> about <a href="../shared/setup.html">setup</a>.</p>
+<ul>
+<li>
+<p>Item:</p>
+<p>A <em>note</em> about <a href="../shared/setup.html">setup</a>.</p>
+</li>
+</ul>
+back:`
+	if out := readFile(t, filepath.Join(tmp, "public/docs/guide.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+
+	expected = "<h1 id=\"run\">Run</h1>\nback:[Guide][Install][Note]"
+	if out := readFile(t, filepath.Join(tmp, "public/shared/setup.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+}
+
+func TestRenderContentIncludeErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		files map[string]string
+		err   string
+	}{
+		"cycle": {
+			files: map[string]string{
+				"content/page.md": "{{% include \"a.md\" %}}\n",
+				"content/a.md":    "A\n\n{{% include \"b.md\" %}}\n",
+				"content/b.md":    "{{% include \"a.md\" %}}\n",
+			},
+			err: `page.md: line 1: include a.md: line 3: include b.md: line 1: include cycle: a.md → b.md → a.md`,
+		},
+		"missing": {
+			files: map[string]string{"content/page.md": "\n{{% include \"gone.md\" %}}\n"},
+			err:   "page.md: line 2: include: open ",
+		},
+		"inline": {
+			files: map[string]string{
+				"content/page.md": "Text {{% include \"a.md\" %}}\n",
+				"content/a.md":    "A\n",
+			},
+			err: `page.md: line 1: shortcode "include" must be on a line of its own`,
+		},
+		"html": {
+			files: map[string]string{
+				"content/page.md": "{{< include \"a.md\" >}}\n",
+				"content/a.md":    "A\n",
+			},
+			err: `page.md: line 1: shortcode "include" must be written {{% include %}}`,
+		},
+		"outside": {
+			files: map[string]string{"content/page.md": "{{% include \"../../elsewhere.md\" %}}\n"},
+			err:   "page.md: line 1: include outside the repository: ../../elsewhere.md",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tmp := t.TempDir()
+			tc.files["site.jsonr"] = `{}`
+			tc.files["templates/base.html"] = `{{.Content}}`
+			writeFiles(t, tmp, tc.files)
+
+			opts := &Options{&RawOptions{SiteDir: tmp}}
+			err := renderContent(opts)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestIncludeDependencies(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		".git/HEAD":                "",
+		"docs/shared.md":           "Shared *text*.\n",
+		"site/site.jsonr":          `{}`,
+		"site/templates/base.html": `{{.Content}}`,
+		"site/includes/local.md":   "Local.\n",
+		"site/content/page.md":     "{{% include \"../../docs/shared.md\" %}}\n\n{{% include \"../includes/local.md\" %}}\n",
+	})
+
+	opts := &Options{&RawOptions{SiteDir: filepath.Join(tmp, "site")}}
+	site, err := renderSite(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "<p>Shared <em>text</em>.</p>\n<p>Local.</p>\n"
+	if out := readFile(t, filepath.Join(tmp, "site/public/page.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+	deps := site.dependencies(opts.SiteDir())
+	if expected := []string{filepath.Join(tmp, "docs/shared.md")}; !slices.Equal(deps, expected) {
+		t.Fatalf("expected dependencies %q, got %q", expected, deps)
+	}
+}
+
+func TestRenderContentIncludeWikiLink(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":             `{}`,
+		"templates/base.html":    `{{.Content}}`,
+		"content/docs/intro.md":  "---\n{\"Title\": \"Intro\"}\n---\n# Top\n\n{{% include \"../shared/part.md\" %}}\n",
+		"content/shared/part.md": "See [[Intro]] and [[intro#top|the top]].\n",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir:  tmp,
+		Strict:   true,
+		Markdown: MarkdownConfig{WikiLinks: ptr(true)},
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "<h1 id=\"top\">Top</h1>\n<p>See <a href=\"intro.html\">Intro</a> and <a href=\"intro.html#top\">the top</a>.</p>\n"
+	if out := readFile(t, filepath.Join(tmp, "public/docs/intro.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+}
+
+func TestRenderContentIncludeHTMLBlock(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{.Content}}`,
+		"content/page.md": "<div class=\"note\">\n" +
+			"{{% include \"part.md\" %}}\n" +
+			"Back in the page.\n",
+		"content/part.md": "See [part](part.md).\n\n<div class=\"box\">\n</div>",
+	})
+
+	if err := renderContent(&Options{&RawOptions{SiteDir: tmp}}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "<div class=\"note\">\n" +
+		"<p>See <a href=\"part.html\">part</a>.</p>\n" +
+		"<div class=\"box\">\n</div>\n" +
+		"<p>Back in the page.</p>\n"
+	if out := readFile(t, filepath.Join(tmp, "public/page.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+}
@@ -0,0 +1,157 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Backlink is a page that links to the current page.
+type Backlink struct {
+	Page *Page
+	Text string // text of the first link from Page
+}
+
+// pageLink is a link as seen by LinkRewriter, before it is resolved to a
+// page.
+type pageLink struct {
+	target string // relative to ContentDir
+	anchor string
+	text   string
+}
+
+func (p *Page) addLink(target, anchor, text string) {
+	p.links = append(p.links, pageLink{target: target, anchor: anchor, text: text})
+}
+
+// setLinks resolves the links recorded while converting pages into OutLinks
+// and Backlinks. Links to missing pages and to the page itself are dropped,
+// and each target only counts once per page. Anchors are checked against
+// the IDs in the target's content, so they must match its heading IDs.
+func (s *site) setLinks() {
+	ids := map[*Page]map[string]bool{}
+	for _, p := range s.pages {
+		p.OutLinks = nil
+		p.Backlinks = nil
+		ids[p] = htmlIDs(string(p.Content))
+	}
+	for _, p := range s.pages {
+		seen := map[*Page]bool{}
+		for _, l := range p.links {
+			target, ok := s.byPath[l.target]
+			if !ok {
+				continue
+			}
+			if anchor, err := url.PathUnescape(l.anchor); l.anchor != "" && (err != nil || !ids[target][anchor]) {
+				p.warn(fmt.Sprintf("broken anchor → %s#%s", filepath.ToSlash(l.target), l.anchor))
+			}
+			if target == p || seen[target] {
+				continue
+			}
+			seen[target] = true
+			p.OutLinks = append(p.OutLinks, target)
+			target.Backlinks = append(target.Backlinks, Backlink{Page: p, Text: l.text})
+		}
+	}
+}
+
+// htmlIDs collects the id attributes in an HTML fragment, along with the
+// names of a elements, which also work as anchors.
+func htmlIDs(htmlStr string) map[string]bool {
+	ids := map[string]bool{}
+	z := html.NewTokenizer(strings.NewReader(htmlStr))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return ids
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		name, hasAttr := z.TagName()
+		for hasAttr {
+			var key, val []byte
+			key, val, hasAttr = z.TagAttr()
+			if string(key) == "id" || (string(key) == "name" && string(name) == "a") {
+				ids[string(val)] = true
+			}
+		}
+	}
+}
+
+// graphNode and graphEdge are the JSON form of the link graph.
+type graphNode struct {
+	Path  string
+	Title string
+	URL   string
+}
+
+type graphEdge struct {
+	Source string
+	Target string
+	Text   string
+}
+
+// WriteGraph loads the site and writes its internal link graph to w in the
+// given format, either "dot" or "json". Nodes are identified by their source
+// path relative to the content directory.
+func WriteGraph(opts *Options, w io.Writer, format string) error {
+	siteConfig, err := readSiteConfig(opts)
+	if err != nil {
+		return err
+	}
+	tmpl, err := loadTemplates(opts)
+	if err != nil {
+		return fmt.Errorf("template load failed: %w", err)
+	}
+	site, err := loadSite(opts, siteConfig, tmpl)
+	if err != nil {
+		return err
+	}
+
+	nodes := []graphNode{}
+	edges := []graphEdge{}
+	for _, p := range site.pages {
+		nodes = append(nodes, graphNode{Path: p.File.Path, Title: p.Title, URL: p.URL})
+		for _, target := range p.OutLinks {
+			for _, bl := range target.Backlinks {
+				if bl.Page == p {
+					edges = append(edges, graphEdge{Source: p.File.Path, Target: target.File.Path, Text: bl.Text})
+				}
+			}
+		}
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]any{"Nodes": nodes, "Edges": edges})
+	case "dot":
+		b := &strings.Builder{}
+		b.WriteString("digraph yugo {\n")
+		for _, n := range nodes {
+			fmt.Fprintf(b, "  %s [label=%s];\n", dotQuote(n.Path), dotQuote(n.Title))
+		}
+		for _, e := range edges {
+			fmt.Fprintf(b, "  %s -> %s [label=%s];\n", dotQuote(e.Source), dotQuote(e.Target), dotQuote(e.Text))
+		}
+		b.WriteString("}\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	return fmt.Errorf("unknown graph format %q", format)
+}
+
+// dotQuote makes a DOT quoted string.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,118 @@
+package build
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestRenderContentBacklinks(t *testing.T) {
+	siteTest{
+		files: map[string]string{
+			"templates/base.html": `out:{{range .Page.OutLinks}}[{{.Title}}]{{end}} back:{{range .Page.Backlinks}}[{{.Page.Title}}: {{.Text}}]{{end}}`,
+			"content/a.md":        "---\n{\"Title\": \"A\"}\n---\nSee [the *B* page](b.md), [B again](b.md#x), [notes](/notes/c.md), [self](a.md) and [gone](gone.md).\n",
+			"content/b.md":        "---\n{\"Title\": \"B\"}\n---\nBack to [A](a.md).\n",
+			"content/notes/c.md":  "---\n{\"Title\": \"C\"}\n---\nUp to [B](../b.md).\n",
+		},
+		output: map[string]string{
+			"a.html":       "out:[B][C] back:[B: A]",
+			"b.html":       "out:[A] back:[A: the B page][C: B]",
+			"notes/c.html": "out:[B] back:[A: notes]",
+		},
+	}.run(t)
+}
+
+func TestWriteGraphShortcodes(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":                      `{}`,
+		"templates/_shortcodes/note.html": "<aside>\n\n{{.Inner}}\n\n</aside>",
+		"content/a.md":                    "{{% note %}}See [b](b.md).{{% /note %}}\n",
+		"content/b.md":                    "",
+	})
+	opts := &Options{&RawOptions{
+		SiteDir: tmp,
+	}}
+
+	out := &bytes.Buffer{}
+	if err := WriteGraph(opts, out, "dot"); err != nil {
+		t.Fatal(err)
+	}
+	expected := `digraph yugo {
+  "a.md" [label=""];
+  "b.md" [label=""];
+  "a.md" -> "b.md" [label="b"];
+}
+`
+	if out.String() != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out.String()))
+	}
+}
+
+func TestWriteGraph(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{.Content}}`,
+		"content/a.md":        "---\n{\"Title\": \"Say \\\"A\\\"\"}\n---\n[to b](b.md)\n",
+		"content/b.md":        "---\n{\"Title\": \"B\"}\n---\n",
+	})
+	opts := &Options{&RawOptions{
+		SiteDir: tmp,
+	}}
+
+	out := &bytes.Buffer{}
+	if err := WriteGraph(opts, out, "dot"); err != nil {
+		t.Fatal(err)
+	}
+	expected := `digraph yugo {
+  "a.md" [label="Say \"A\""];
+  "b.md" [label="B"];
+  "a.md" -> "b.md" [label="to b"];
+}
+`
+	if out.String() != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out.String()))
+	}
+
+	out.Reset()
+	if err := WriteGraph(opts, out, "json"); err != nil {
+		t.Fatal(err)
+	}
+	graph := struct {
+		Nodes []graphNode
+		Edges []graphEdge
+	}{}
+	if err := json.Unmarshal(out.Bytes(), &graph); err != nil {
+		t.Fatal(err)
+	}
+	if len(graph.Nodes) != 2 || graph.Nodes[1].URL != "/b.html" {
+		t.Fatalf("unexpected nodes: %+v", graph.Nodes)
+	}
+	if len(graph.Edges) != 1 || graph.Edges[0] != (graphEdge{Source: "a.md", Target: "b.md", Text: "to b"}) {
+		t.Fatalf("unexpected edges: %+v", graph.Edges)
+	}
+
+	if err := WriteGraph(opts, out, "svg"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestRenderContentHTMLLinks(t *testing.T) {
+	siteTest{
+		files: map[string]string{
+			"templates/base.html": `{{.Content}}|{{range .Page.Backlinks}}[{{.Page.File.Path}}: {{.Text}}]{{end}}`,
+			"content/a.md":        "<div>\n<a href=\"b.md\">block</a>\n</div>\n\nInline <a href=\"c.html#x\">skip</a> and <a href=\"b.md\">again</a>.\n\n    <a href=\"b.md\">code</a>\n",
+			"content/b.md":        "",
+			"content/c.html":      `<p><a href="a.md">html page</a></p>`,
+		},
+		output: map[string]string{
+			"a.html": "<div>\n<a href=\"b.html\">block</a>\n</div>\n" +
+				"<p>Inline <a href=\"c.html#x\">skip</a> and <a href=\"b.html\">again</a>.</p>\n" +
+				"<pre><code>&lt;a href=&quot;b.md&quot;&gt;code&lt;/a&gt;\n</code></pre>\n" +
+				"|[c.html: html page]",
+			"b.html": "|[a.md: block]",
+			"c.html": `<p><a href="a.html">html page</a></p>|`,
+		},
+	}.run(t)
+}
@@ -0,0 +1,204 @@
+package build
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// MarkdownConfig selects goldmark extensions and options. It is read from
+// the Markdown key of yugo.jsonr and can be overridden by the Markdown key
+// of a page's frontmatter. Unset fields keep their defaults, which are
+// GitHub Flavored Markdown with Typographer and raw HTML.
+type MarkdownConfig struct {
+	Footnote       *bool
+	DefinitionList *bool
+	CJK            *bool
+	Linkify        *bool
+	Strikethrough  *bool
+	Table          *bool
+	TaskList       *bool
+	Typographer    *bool
+	Alerts         *bool // GitHub's > [!NOTE] blockquotes, see Alerts
+	WikiLinks      *bool // [[links]] to pages by name, see WikiLinks
+	// Quotes replaces Typographer substitutions, keyed by goldmark's names
+	// like "LeftDoubleQuote". Values are HTML.
+	Quotes map[string]string
+
+	// Math parses $inline$ and $$display$$ TeX, see MathExtension.
+	// MathOutput is "mathml", the default, or "passthrough".
+	Math       *bool
+	MathOutput string
+
+	// Highlight renders fenced code blocks in a known language with CSS
+	// classes, see Highlighter.
+	Highlight *bool
+
+	Attributes *bool // {#id .class} after headings
+	HardWraps  *bool // render soft line breaks as <br>
+	XHTML      *bool
+	Unsafe     *bool // render raw HTML instead of omitting it
+}
+
+var defaultMarkdownConfig = MarkdownConfig{
+	Footnote:       new(bool),
+	DefinitionList: new(bool),
+	CJK:            new(bool),
+	Linkify:        ptr(true),
+	Strikethrough:  ptr(true),
+	Table:          ptr(true),
+	TaskList:       ptr(true),
+	Typographer:    ptr(true),
+	Alerts:         ptr(true),
+	WikiLinks:      new(bool),
+	Math:           new(bool),
+	MathOutput:     mathOutputMathML,
+	Highlight:      new(bool),
+	Attributes:     new(bool),
+	HardWraps:      new(bool),
+	XHTML:          new(bool),
+	Unsafe:         ptr(true),
+}
+
+var typographicNames = map[string]extension.TypographicPunctuation{
+	"LeftSingleQuote":  extension.LeftSingleQuote,
+	"RightSingleQuote": extension.RightSingleQuote,
+	"LeftDoubleQuote":  extension.LeftDoubleQuote,
+	"RightDoubleQuote": extension.RightDoubleQuote,
+	"EnDash":           extension.EnDash,
+	"EmDash":           extension.EmDash,
+	"Ellipsis":         extension.Ellipsis,
+	"LeftAngleQuote":   extension.LeftAngleQuote,
+	"RightAngleQuote":  extension.RightAngleQuote,
+	"Apostrophe":       extension.Apostrophe,
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func (mc MarkdownConfig) check() error {
+	for name := range mc.Quotes {
+		if _, ok := typographicNames[name]; !ok {
+			return fmt.Errorf("unknown Quotes key %q", name)
+		}
+	}
+	switch mc.MathOutput {
+	case "", mathOutputMathML, mathOutputPassthrough:
+	default:
+		return fmt.Errorf("invalid MathOutput %q, expected %q or %q", mc.MathOutput, mathOutputMathML, mathOutputPassthrough)
+	}
+	return nil
+}
+
+// asMarkdownConfig decodes the Markdown frontmatter key. Unknown keys are
+// errors so that a typo doesn't silently keep the site setting.
+func asMarkdownConfig(v any) (MarkdownConfig, error) {
+	mc := MarkdownConfig{}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return mc, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&mc); err != nil {
+		return mc, err
+	}
+	return mc, mc.check()
+}
+
+// overlay returns mc with every field set in o replacing its own.
+func (mc MarkdownConfig) overlay(o MarkdownConfig) MarkdownConfig {
+	or := func(a, b *bool) *bool {
+		if b != nil {
+			return b
+		}
+		return a
+	}
+	quotes := maps.Clone(mc.Quotes)
+	if quotes == nil {
+		quotes = map[string]string{}
+	}
+	maps.Copy(quotes, o.Quotes)
+	mathOutput := mc.MathOutput
+	if o.MathOutput != "" {
+		mathOutput = o.MathOutput
+	}
+	return MarkdownConfig{
+		Footnote:       or(mc.Footnote, o.Footnote),
+		DefinitionList: or(mc.DefinitionList, o.DefinitionList),
+		CJK:            or(mc.CJK, o.CJK),
+		Linkify:        or(mc.Linkify, o.Linkify),
+		Strikethrough:  or(mc.Strikethrough, o.Strikethrough),
+		Table:          or(mc.Table, o.Table),
+		TaskList:       or(mc.TaskList, o.TaskList),
+		Typographer:    or(mc.Typographer, o.Typographer),
+		Alerts:         or(mc.Alerts, o.Alerts),
+		WikiLinks:      or(mc.WikiLinks, o.WikiLinks),
+		Quotes:         quotes,
+		Math:           or(mc.Math, o.Math),
+		MathOutput:     mathOutput,
+		Highlight:      or(mc.Highlight, o.Highlight),
+		Attributes:     or(mc.Attributes, o.Attributes),
+		HardWraps:      or(mc.HardWraps, o.HardWraps),
+		XHTML:          or(mc.XHTML, o.XHTML),
+		Unsafe:         or(mc.Unsafe, o.Unsafe),
+	}
+}
+
+// goldmarkOptions turns a config with every field set, as returned by
+// Options.Markdown, into goldmark options.
+func (mc MarkdownConfig) goldmarkOptions() ([]goldmark.Extender, []parser.Option, []renderer.Option) {
+	exts := []goldmark.Extender{}
+	for _, e := range []struct {
+		on  *bool
+		ext goldmark.Extender
+	}{
+		{mc.Footnote, extension.Footnote},
+		{mc.DefinitionList, extension.DefinitionList},
+		{mc.CJK, extension.CJK},
+		{mc.Linkify, extension.Linkify},
+		{mc.Strikethrough, extension.Strikethrough},
+		{mc.Table, extension.Table},
+		{mc.TaskList, extension.TaskList},
+		{mc.Alerts, Alerts{}},
+	} {
+		if *e.on {
+			exts = append(exts, e.ext)
+		}
+	}
+	if *mc.Typographer {
+		subs := extension.TypographicSubstitutions{}
+		for name, sub := range mc.Quotes {
+			subs[typographicNames[name]] = []byte(sub)
+		}
+		exts = append(exts, extension.NewTypographer(extension.WithTypographicSubstitutions(subs)))
+	}
+
+	parserOpts := []parser.Option{}
+	if *mc.Attributes {
+		parserOpts = append(parserOpts, parser.WithAttribute())
+	}
+
+	rendererOpts := []renderer.Option{}
+	for _, o := range []struct {
+		on  *bool
+		opt renderer.Option
+	}{
+		{mc.HardWraps, html.WithHardWraps()},
+		{mc.XHTML, html.WithXHTML()},
+		{mc.Unsafe, html.WithUnsafe()},
+	} {
+		if *o.on {
+			rendererOpts = append(rendererOpts, o.opt)
+		}
+	}
+	return exts, parserOpts, rendererOpts
+}
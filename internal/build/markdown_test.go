@@ -0,0 +1,78 @@
+package build
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestRenderContentMarkdownConfig(t *testing.T) {
+	body := "# Title {#custom .big}\n\n\"Quoted\" -- text[^1]\nwraps <b>raw</b> https://example.com ~~gone~~\n\n[^1]: Note.\n"
+	siteTest{
+		files: map[string]string{
+			"templates/base.html": `{{.Content}}`,
+			"content/site.md":     body,
+			"content/page.md":     "---\n{\"Markdown\": {\"Footnote\": false, \"Typographer\": false, \"Unsafe\": false}}\n---\n" + body,
+		},
+		opts: RawOptions{
+			Markdown: MarkdownConfig{
+				Footnote:      ptr(true),
+				Attributes:    ptr(true),
+				HardWraps:     ptr(true),
+				XHTML:         ptr(true),
+				Strikethrough: ptr(false),
+				Quotes:        map[string]string{"LeftDoubleQuote": "&laquo;", "RightDoubleQuote": "&raquo;"},
+			},
+		},
+		output: map[string]string{
+			"site.html": `<h1 id="custom" class="big">Title</h1>` + "\n" +
+				`<p>&laquo;Quoted&raquo; &ndash; text<sup id="fnref:1"><a href="#fn:1" class="footnote-ref" role="doc-noteref">1</a></sup><br />` + "\n" +
+				`wraps <b>raw</b> <a href="https://example.com">https://example.com</a> ~~gone~~</p>` + "\n" +
+				`<div class="footnotes" role="doc-endnotes">` + "\n<hr />\n<ol>\n" + `<li id="fn:1">` + "\n" +
+				`<p>Note.&#160;<a href="#fnref:1" class="footnote-backref" role="doc-backlink">&#x21a9;&#xfe0e;</a></p>` + "\n</li>\n</ol>\n</div>\n",
+			// Without Footnote, the note is a link reference definition.
+			"page.html": `<h1 id="custom" class="big">Title</h1>` + "\n" +
+				`<p>&quot;Quoted&quot; -- text<a href="Note.">^1</a><br />` + "\n" +
+				`wraps <!-- raw HTML omitted -->raw<!-- raw HTML omitted --> <a href="https://example.com">https://example.com</a> ~~gone~~</p>` + "\n",
+		},
+	}.run(t)
+}
+
+func TestMarkdownConfigOverlay(t *testing.T) {
+	site := MarkdownConfig{
+		Footnote:   ptr(true),
+		Math:       ptr(true),
+		MathOutput: "mathml",
+		Quotes:     map[string]string{"LeftDoubleQuote": "&laquo;", "RightDoubleQuote": "&raquo;"},
+	}
+	page := MarkdownConfig{
+		Footnote: ptr(false),
+		Unsafe:   ptr(false),
+		Quotes:   map[string]string{"RightDoubleQuote": "&rdquo;"},
+	}
+	mc := site.overlay(page)
+	if *mc.Footnote || *mc.Unsafe || !*mc.Math || mc.Typographer != nil {
+		t.Fatalf("unexpected flags: %+v", mc)
+	}
+	if mc.MathOutput != "mathml" {
+		t.Fatalf("expected MathOutput to be kept, got %q", mc.MathOutput)
+	}
+	expected := map[string]string{"LeftDoubleQuote": "&laquo;", "RightDoubleQuote": "&rdquo;"}
+	if !maps.Equal(expected, mc.Quotes) {
+		t.Fatalf("expected quotes %q, got %q", expected, mc.Quotes)
+	}
+	if site.Quotes["RightDoubleQuote"] != "&raquo;" {
+		t.Fatal("overlay modified the base quotes")
+	}
+}
+
+func TestParsePageMarkdownError(t *testing.T) {
+	_, err := ParsePage([]byte("---\n{\"Markdown\": {\"Footnotes\": true}}\n---\n"), nil)
+	if err == nil || !strings.Contains(err.Error(), `frontmatter key "Markdown": json: unknown field "Footnotes"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = ParsePage([]byte("---\n{\"Markdown\": {\"Quotes\": {\"Left\": \"x\"}}}\n---\n"), nil)
+	if err == nil || !strings.Contains(err.Error(), `unknown Quotes key "Left"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package build
+
+import (
+	"bytes"
+	"html"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Math output modes for the MathOutput option.
+const (
+	mathOutputMathML      = "mathml"
+	mathOutputPassthrough = "passthrough"
+)
+
+// KindMath is the ast.NodeKind of Math.
+var KindMath = ast.NewNodeKind("Math")
+
+// Math is TeX between $ or $$ delimiters within a paragraph.
+type Math struct {
+	ast.BaseInline
+	TeX     string
+	Display bool // written with $$
+}
+
+func (n *Math) Kind() ast.NodeKind {
+	return KindMath
+}
+
+func (n *Math) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"TeX": n.TeX}, nil)
+}
+
+// KindMathBlock is the ast.NodeKind of MathBlock.
+var KindMathBlock = ast.NewNodeKind("MathBlock")
+
+// MathBlock is TeX on lines between $$ lines. The TeX is in its Lines.
+type MathBlock struct {
+	ast.BaseBlock
+	closed bool // the closing $$ has been read
+}
+
+func (n *MathBlock) Kind() ast.NodeKind {
+	return KindMathBlock
+}
+
+func (n *MathBlock) IsRaw() bool {
+	return true
+}
+
+func (n *MathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// MathExtension parses $inline$ and $$display$$ TeX, whose content is left
+// alone by every other Markdown rule. Output is "mathml" to convert it with
+// texToMathML, or "passthrough" to leave it in \(...\) and \[...\] for a
+// client-side renderer like KaTeX or MathJax.
+type MathExtension struct {
+	Output string
+	Warn   func(msg string)
+}
+
+func (e MathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithInlineParsers(util.Prioritized(mathParser{}, 150)),
+		parser.WithBlockParsers(util.Prioritized(mathBlockParser{}, 700)),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(mathRenderer{e}, 500),
+	))
+}
+
+type mathParser struct{}
+
+func (p mathParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Parse reads $$...$$, or $...$ where the opening $ isn't followed by a
+// space and the closing $ isn't preceded by a space or followed by a digit.
+// The closing $ is the next unescaped one, so if that can't close, the
+// opening $ is text. This keeps prices like $5 and $10 as text, even when
+// math follows them on the same line.
+func (p mathParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if bytes.HasPrefix(line, []byte("$$")) {
+		end := bytes.Index(line[2:], []byte("$$"))
+		if end <= 0 {
+			return nil
+		}
+		block.Advance(end + 4)
+		return &Math{TeX: string(line[2 : end+2]), Display: true}
+	}
+
+	if len(line) < 3 || isMathSpace(line[1]) {
+		return nil
+	}
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '$':
+			if isMathSpace(line[i-1]) || (i+1 < len(line) && line[i+1] >= '0' && line[i+1] <= '9') {
+				return nil
+			}
+			block.Advance(i + 1)
+			return &Math{TeX: string(line[1:i])}
+		}
+	}
+	return nil
+}
+
+func isMathSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+type mathBlockParser struct{}
+
+func (p mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Open starts a block at a line beginning with $$. The block may end on the
+// same line, as long as nothing follows the closing $$.
+func (p mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, seg := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+	rest := seg.WithStart(seg.Start + pos + 2)
+	node := &MathBlock{}
+	if tex, ok := mathBlockEnd(rest, reader.Source()); ok {
+		if bytes.Contains(tex.Value(reader.Source()), []byte("$$")) {
+			// Like $$a$$ and $$b$$, which is a paragraph.
+			return nil, parser.NoChildren
+		}
+		node.Lines().Append(tex)
+		node.closed = true
+	} else if !util.IsBlank(rest.Value(reader.Source())) {
+		node.Lines().Append(rest)
+	}
+	reader.AdvanceToEOL()
+	return node, parser.NoChildren
+}
+
+func (p mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	block := node.(*MathBlock)
+	if block.closed {
+		return parser.Close
+	}
+	_, seg := reader.PeekLine()
+	if tex, ok := mathBlockEnd(seg, reader.Source()); ok {
+		if !util.IsBlank(tex.Value(reader.Source())) {
+			block.Lines().Append(tex)
+		}
+		reader.AdvanceToEOL()
+		block.closed = true
+		return parser.Close
+	}
+	block.Lines().Append(seg)
+	reader.AdvanceToEOL()
+	return parser.Continue | parser.NoChildren
+}
+
+// mathBlockEnd returns the part of a line before a closing $$ at its end.
+func mathBlockEnd(seg text.Segment, source []byte) (text.Segment, bool) {
+	value := bytes.TrimRight(seg.Value(source), " \t\r\n")
+	if !bytes.HasSuffix(value, []byte("$$")) {
+		return seg, false
+	}
+	return text.NewSegment(seg.Start, seg.Start+len(value)-2), true
+}
+
+func (p mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+type mathRenderer struct {
+	MathExtension
+}
+
+func (r mathRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMath, r.renderMath)
+	reg.Register(KindMathBlock, r.renderMathBlock)
+}
+
+func (r mathRenderer) renderMath(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		m := n.(*Math)
+		r.write(w, m.TeX, m.Display, "span")
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func (r mathRenderer) renderMathBlock(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		tex := strings.Builder{}
+		for i := 0; i < n.Lines().Len(); i++ {
+			line := n.Lines().At(i)
+			tex.Write(line.Value(source))
+		}
+		r.write(w, strings.TrimSpace(tex.String()), true, "div")
+		_ = w.WriteByte('\n')
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// write renders TeX in a passthrough element, which is a span or a div, or
+// as MathML.
+func (r mathRenderer) write(w util.BufWriter, tex string, display bool, element string) {
+	if r.Output == mathOutputPassthrough {
+		class, open, close := "math inline", `\(`, `\)`
+		if display {
+			class, open, close = "math display", `\[`, `\]`
+		}
+		_, _ = w.WriteString("<" + element + ` class="` + class + `">` + open + html.EscapeString(tex) + close + "</" + element + ">")
+		return
+	}
+	mathML, problems := texToMathML(tex, display)
+	for _, p := range problems {
+		r.Warn("math: " + p)
+	}
+	_, _ = w.WriteString(mathML)
+}
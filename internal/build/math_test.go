@@ -0,0 +1,99 @@
+package build
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+)
+
+func TestRenderContentMath(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{.Content}}`,
+		"content/page.md": "Inline $a_b * c_d$ costs $5 and $10.\n\n\\$x$ stays.\n\n" +
+			"Display $$x \"y\"$$ inline.\n\n" +
+			"$$\n\\frac{a}{b} -- c\n$$\n\n" +
+			"$$ x^2 $$\n",
+		"content/off.md": "---\n{\"Markdown\": {\"Math\": false}}\n---\n$a *b* c$\n",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir:  tmp,
+		Markdown: MarkdownConfig{Math: ptr(true), MathOutput: mathOutputPassthrough},
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<p>Inline <span class="math inline">\(a_b * c_d\)</span> costs $5 and $10.</p>
+<p>$x$ stays.</p>
+<p>Display <span class="math display">\[x &#34;y&#34;\]</span> inline.</p>
+<div class="math display">\[\frac{a}{b} -- c\]</div>
+<div class="math display">\[x^2\]</div>
+`
+	if out := readFile(t, filepath.Join(tmp, "public/page.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+
+	expected = "<p>$a <em>b</em> c$</p>\n"
+	if out := readFile(t, filepath.Join(tmp, "public/off.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+}
+
+func TestRenderContentMathML(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{.Content}}`,
+		"content/page.md":     "Area $\\pi r^2$.\n\n$$\n\\sqrt{x}\n$$\n",
+		"content/bad.md":      "$\\nope$\n",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir:  tmp,
+		Markdown: MarkdownConfig{Math: ptr(true)},
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<p>Area <math><semantics><mrow><mi>π</mi><msup><mi>r</mi><mn>2</mn></msup></mrow><annotation encoding="application/x-tex">\pi r^2</annotation></semantics></math>.</p>
+<math display="block"><semantics><msqrt><mi>x</mi></msqrt><annotation encoding="application/x-tex">\sqrt{x}</annotation></semantics></math>
+`
+	if out := readFile(t, filepath.Join(tmp, "public/page.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+
+	opts.rawOptions.Strict = true
+	err := renderContent(opts)
+	if err == nil || !strings.Contains(err.Error(), `bad.md: math: unsupported TeX command \nope`) {
+		t.Fatalf("expected an unsupported command error, got %v", err)
+	}
+}
+
+func TestMathInlineDelimiters(t *testing.T) {
+	md := goldmark.New(goldmark.WithExtensions(MathExtension{Output: mathOutputPassthrough}))
+	for in, expected := range map[string]string{
+		"$a$ and $b$":                 `<span class="math inline">\(a\)</span> and <span class="math inline">\(b\)</span>`,
+		"Price $5 and $a_b * c$":      `Price $5 and <span class="math inline">\(a_b * c\)</span>`,
+		"$5 and $10":                  `$5 and $10`,
+		"$x$5":                        `$x$5`,
+		"$ x$":                        `$ x$`,
+		"$\\$ x$":                     `<span class="math inline">\(\$ x\)</span>`,
+		"costs $5, or $x $y$ for you": `costs $5, or $x <span class="math inline">\(y\)</span> for you`,
+	} {
+		buf := &bytes.Buffer{}
+		if err := md.Convert([]byte(in), buf); err != nil {
+			t.Fatal(err)
+		}
+		out := strings.TrimSuffix(strings.TrimPrefix(buf.String(), "<p>"), "</p>\n")
+		if out != expected {
+			t.Errorf("%q: expected %s, got %s", in, expected, out)
+		}
+	}
+}
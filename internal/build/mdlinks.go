@@ -2,13 +2,18 @@ package build
 
 import (
 	"fmt"
+	"html"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
+	nethtml "golang.org/x/net/html"
 )
 
 var SourceFileKey = parser.NewContextKey()
@@ -16,17 +21,54 @@ var SourceFileKey = parser.NewContextKey()
 type LinkRewriter struct {
 	SiteDir    string
 	ContentDir string
+	// Pages are the site's pages keyed by path relative to ContentDir. If a
+	// link target is found here, its output name is used in the rewritten
+	// link.
+	Pages map[string]*Page
+	// Warn is called for each problem found. If nil, warnings are printed to
+	// stderr.
+	Warn func(msg string)
+	// Link is called for each internal link to a Markdown file, with the
+	// target path relative to ContentDir, the anchor if any, and the text of
+	// the link.
+	Link func(destPath, anchor, text string)
+	// RepoURL is a template like "https://git.example.com/blob/main/{path}#L{line}"
+	// for links to files outside ContentDir. {path} is relative to RepoDir.
+	// If empty, those links are left alone.
+	RepoURL string
+	RepoDir string
+}
+
+func (r LinkRewriter) warn(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if r.Warn != nil {
+		r.Warn(msg)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "WARN: %s\n", msg)
 }
 
 func (r LinkRewriter) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
 	// Location of the markdown source file relative to ContentDir.
 	srcFile := pc.Get(SourceFileKey).(string)
+	// Links in included files are resolved relative to those files.
+	included, _ := pc.Get(IncludedFilesKey).(map[ast.Node]string)
 
 	walkErr := ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		if !entering {
 			return ast.WalkContinue, nil
 		}
 
+		if img, ok := n.(*ast.Image); ok {
+			if file, ok := included[img]; ok {
+				img.Destination = []byte(rebaseRef(string(img.Destination), file, srcFile))
+			}
+			return ast.WalkContinue, nil
+		}
+		if auto, ok := n.(*ast.AutoLink); ok {
+			r.rewriteAutoLink(auto, srcFile, reader.Source())
+			return ast.WalkContinue, nil
+		}
 		link, ok := n.(*ast.Link)
 		if !ok {
 			return ast.WalkContinue, nil
@@ -39,55 +81,295 @@ func (r LinkRewriter) Transform(node *ast.Document, reader text.Reader, pc parse
 			}
 		}
 
-		dest := string(link.Destination)
+		file, ok := included[link]
+		if !ok {
+			file = srcFile
+		}
+		if dest, ok := r.rewrite(file, string(link.Destination), nodeText(link, reader.Source())); ok {
+			link.Destination = []byte(dest)
+		}
+		if file != srcFile {
+			link.Destination = []byte(rebaseRef(string(link.Destination), file, srcFile))
+		}
+		return ast.WalkContinue, nil
+	})
+	if walkErr != nil {
+		r.warn("error processing Markdown links: %s", walkErr)
+	}
+}
 
-		// Skip external links
-		if isExternal(dest) {
-			return ast.WalkContinue, nil
+// rewriteAutoLink resolves an autolink like any other link, replacing it with
+// a plain link if it is rewritten. Autolinks in CommonMark and from Linkify
+// always have a scheme, so this only leaves them alone today, but it keeps
+// them from bypassing the rules if a parser ever produces a local one.
+func (r LinkRewriter) rewriteAutoLink(auto *ast.AutoLink, srcFile string, source []byte) {
+	if auto.AutoLinkType != ast.AutoLinkURL {
+		return
+	}
+	label := string(auto.Label(source))
+	dest, ok := r.rewrite(srcFile, string(auto.URL(source)), label)
+	if !ok {
+		return
+	}
+	link := ast.NewLink()
+	link.Destination = []byte(dest)
+	link.AppendChild(link, ast.NewString([]byte(label)))
+	auto.Parent().ReplaceChild(auto.Parent(), auto, link)
+}
+
+// RewriteHTML rewrites the href of HTML elements that point at .md files,
+// using the same resolution and warnings as Markdown links. Goldmark keeps
+// raw HTML as source text, so this runs on rendered Markdown to cover raw
+// HTML blocks and inline HTML, and on .html content files. Everything else is
+// copied byte for byte.
+func (r LinkRewriter) RewriteHTML(srcFile, htmlStr string) string {
+	// Links are reported once the text of their <a> element is known.
+	type pendingLink struct{ destPath, anchor string }
+	var pending *pendingLink
+	text := strings.Builder{}
+	flush := func() {
+		if pending != nil && r.Link != nil {
+			r.Link(pending.destPath, pending.anchor, strings.Join(strings.Fields(text.String()), " "))
 		}
+		pending = nil
+		text.Reset()
+	}
+	lr := r
+	lr.Link = func(destPath, anchor, _ string) {
+		flush()
+		pending = &pendingLink{destPath, anchor}
+	}
 
-		// Split anchor for .md#section pattern
-		base, anchor := splitAnchor(dest)
+	out := strings.Builder{}
+	z := nethtml.NewTokenizer(strings.NewReader(htmlStr))
+	for {
+		tt := z.Next()
+		if tt == nethtml.ErrorToken {
+			break
+		}
+		// Copy the raw bytes before Token lowercases them in place.
+		raw := string(z.Raw())
+		tok := z.Token()
 
-		// Only rewrite links pointing to .md files
-		if !strings.HasSuffix(base, ".md") {
-			return ast.WalkContinue, nil
+		switch tt {
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			outer := pending
+			changed := false
+			for i, attr := range tok.Attr {
+				if attr.Namespace != "" || attr.Key != "href" || !isMarkdownRef(attr.Val) {
+					continue
+				}
+				if dest, ok := lr.rewrite(srcFile, attr.Val, ""); ok {
+					tok.Attr[i].Val = dest
+					changed = true
+				}
+			}
+			if pending != outer && tok.Data != "a" {
+				// Elements like <link> have no text.
+				flush()
+			}
+			if changed {
+				raw = tok.String()
+			}
+		case nethtml.TextToken:
+			text.WriteString(tok.Data)
+		case nethtml.EndTagToken:
+			if tok.Data == "a" {
+				flush()
+			}
 		}
+		out.WriteString(raw)
+	}
+	flush()
+	return out.String()
+}
+
+// isMarkdownRef reports whether a link points at a local .md file.
+func isMarkdownRef(dest string) bool {
+	base, _ := splitAnchor(dest)
+	return !isExternal(dest) && strings.HasSuffix(base, ".md")
+}
+
+// rewrite resolves a link destination written in srcFile and returns what it
+// should point to in the output, or false to leave it as is.
+func (r LinkRewriter) rewrite(srcFile, dest, text string) (string, bool) {
+	// Skip external links
+	if isExternal(dest) {
+		return "", false
+	}
+
+	// Split anchor for .md#section pattern
+	base, anchor := splitAnchor(dest)
+	if base == "" {
+		return "", false
+	}
+
+	// Paths are resolved decoded, like my%20page.md, and written back as
+	// they were.
+	filePath, err := url.PathUnescape(base)
+	if err != nil {
+		r.warn("invalid link → %s: %s", dest, err)
+		return "", false
+	}
+	// destPath is relative to ContentDir
+	destPath := ""
+	if filepath.IsAbs(filePath) {
+		destPath = filepath.Clean(filePath)
+	} else {
+		destPath = filepath.Clean(filepath.Join(filepath.Dir(srcFile), filePath))
+	}
+	fullDestPath := filepath.Join(r.ContentDir, destPath)
 
-		// destPath is relative to ContentDir
-		destPath := ""
-		if filepath.IsAbs(base) {
-			destPath = filepath.Clean(base)
-		} else {
-			destPath = filepath.Clean(filepath.Join(filepath.Dir(srcFile), base))
+	if r.RepoURL != "" && !filepath.IsAbs(destPath) && !filepath.IsLocal(destPath) {
+		return r.repoLink(dest, fullDestPath, anchor)
+	}
+
+	if !strings.HasSuffix(base, ".md") {
+		if info, err := os.Stat(fullDestPath); err == nil && info.IsDir() {
+			return r.dirLink(dest, base, destPath, anchor, text)
+		}
+		return "", false
+	}
+
+	if _, err := os.Stat(fullDestPath); err != nil {
+		r.warn("broken link → %s (resolved as %s)", dest, destPath)
+	}
+	if r.Link != nil {
+		r.Link(strings.TrimPrefix(destPath, string(filepath.Separator)), anchor, text)
+	}
+
+	// Now rewrite the URL to .html (keeping the resolved path)
+	htmlPath := strings.TrimSuffix(base, ".md") + ".html"
+	if page, ok := r.Pages[destPath]; ok && page.Slug != "" {
+		htmlPath = path.Join(path.Dir(base), path.Base(filepath.ToSlash(page.outPath)))
+	}
+	return withAnchor(htmlPath, anchor), true
+}
+
+// Files that stand in for a directory, in order of preference. GitHub shows
+// the README of a directory, but an index page is what yugo serves there.
+var dirIndexNames = []string{"index.md", "index.html", "README.md", "readme.md"}
+
+// dirLink rewrites a link to a content directory to the page shown for it.
+func (r LinkRewriter) dirLink(dest, base, destPath, anchor, text string) (string, bool) {
+	for _, name := range dirIndexNames {
+		pagePath := filepath.Join(destPath, name)
+		if _, err := os.Stat(filepath.Join(r.ContentDir, pagePath)); err != nil {
+			continue
+		}
+		if r.Link != nil {
+			r.Link(strings.TrimPrefix(pagePath, string(filepath.Separator)), anchor, text)
 		}
-		fullDestPath := filepath.Join(r.ContentDir, destPath)
 
-		// println("dest", dest)
-		// println("destPath", destPath)
-		// println("fullDestPath", fullDestPath)
-		if _, err := os.Stat(fullDestPath); err != nil {
-			fmt.Fprintf(os.Stderr, "WARN: broken link → %s (resolved as %s)\n", dest, destPath)
+		dir := strings.TrimSuffix(base, "/") + "/"
+		if strings.HasPrefix(name, "index.") {
+			return withAnchor(dir, anchor), true
 		}
+		outName := strings.TrimSuffix(name, ".md") + ".html"
+		if page, ok := r.Pages[pagePath]; ok {
+			outName = path.Base(filepath.ToSlash(page.outPath))
+		}
+		return withAnchor(dir+outName, anchor), true
+	}
+	r.warn("directory has no index or README → %s (resolved as %s)", dest, destPath)
+	return "", false
+}
 
-		// Now rewrite the URL to .html (keeping the resolved path)
-		htmlPath := strings.TrimSuffix(base, ".md") + ".html"
-		if anchor != "" {
-			htmlPath += "#" + anchor
+// repoLink rewrites a link to a file outside ContentDir using the RepoURL
+// template. A GitHub style line anchor like #L10 or #L10-L20 fills {line};
+// without one, a fragment using {line} is dropped.
+func (r LinkRewriter) repoLink(dest, fullDestPath, anchor string) (string, bool) {
+	repoPath, err := absRel(r.RepoDir, fullDestPath)
+	if err != nil || !filepath.IsLocal(repoPath) {
+		r.warn("link outside the repository → %s", dest)
+		return "", false
+	}
+	if _, err := os.Stat(fullDestPath); err != nil {
+		r.warn("broken link → %s (resolved as %s)", dest, repoPath)
+	}
+
+	line := ""
+	if m := lineAnchor.FindStringSubmatch(anchor); m != nil {
+		line = m[1]
+	}
+	tmpl := r.RepoURL
+	if line == "" {
+		if base, fragment, ok := strings.Cut(tmpl, "#"); ok && strings.Contains(fragment, "{line}") {
+			tmpl = base
 		}
+	}
+	out := strings.NewReplacer(
+		"{path}", (&url.URL{Path: filepath.ToSlash(repoPath)}).EscapedPath(),
+		"{line}", line,
+	).Replace(tmpl)
+	if line == "" && anchor != "" && !strings.Contains(out, "#") {
+		out += "#" + anchor
+	}
+	return out, true
+}
+
+// absRel is filepath.Rel for paths that may be relative to different
+// places, like a repository found above the working directory.
+func absRel(basePath, targPath string) (string, error) {
+	base, err := filepath.Abs(basePath)
+	if err != nil {
+		return "", err
+	}
+	targ, err := filepath.Abs(targPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(base, targ)
+}
+
+var lineAnchor = regexp.MustCompile(`^L(\d+(?:-L\d+)?)$`)
+
+func withAnchor(p, anchor string) string {
+	if anchor != "" {
+		return p + "#" + anchor
+	}
+	return p
+}
 
-		link.Destination = []byte(htmlPath)
+// nodeText returns the text inside a node without any markup or shortcode
+// placeholders. Soft line breaks become spaces.
+func nodeText(node ast.Node, source []byte) string {
+	b := strings.Builder{}
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			// Typographer substitutions are HTML entities.
+			if n.IsCode() {
+				b.WriteString(html.UnescapeString(string(n.Value)))
+			} else {
+				b.Write(n.Value)
+			}
+		case *Math:
+			b.WriteString(n.TeX)
+		}
 		return ast.WalkContinue, nil
 	})
-	if walkErr != nil {
-		fmt.Fprintf(os.Stderr, "WARN: error processing Markdown links: %s\n", walkErr)
-	}
+	return shortcodePlaceholder.ReplaceAllString(b.String(), "")
 }
 
+// isExternal reports whether a link has a scheme, like https: or mailto:,
+// or a host.
 func isExternal(s string) bool {
-	return strings.HasPrefix(s, "http://") ||
-		strings.HasPrefix(s, "https://") ||
-		strings.HasPrefix(s, "//")
+	u, err := url.Parse(s)
+	if err != nil {
+		return strings.HasPrefix(s, "http://") ||
+			strings.HasPrefix(s, "https://") ||
+			strings.HasPrefix(s, "//")
+	}
+	return u.Scheme != "" || u.Host != "" || strings.HasPrefix(s, "//")
 }
 
 // splitAnchor("docs/x.md#sec") → ("docs/x.md", "sec")
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer/html"
 	"github.com/yuin/goldmark/util"
@@ -20,26 +22,24 @@ func renderWith(t *testing.T, site string, mdText string) string {
 
 func renderWithPath(t *testing.T, site string, mdPath string, mdText string) string {
 	t.Helper()
-
 	mr := LinkRewriter{
 		SiteDir:    site,
 		ContentDir: site + "/content",
 	}
+	return convertWith(t, mr, mdPath, mdText, goldmark.WithRendererOptions(html.WithUnsafe())) // Allow raw HTML in markdown
+}
 
-	md := goldmark.New(
-		goldmark.WithRendererOptions(html.WithUnsafe()), // Allow raw HTML in markdown
-		goldmark.WithParserOptions(
-			parser.WithASTTransformers(
-				util.Prioritized(mr, 100),
-			),
-		),
-	)
-
+// convertWith renders Markdown written in srcFile with a link rewriter.
+func convertWith(t *testing.T, mr LinkRewriter, srcFile, src string, opts ...goldmark.Option) string {
+	t.Helper()
+	md := goldmark.New(append(opts, goldmark.WithParserOptions(
+		parser.WithASTTransformers(util.Prioritized(mr, 100)),
+	))...)
 	pc := parser.NewContext()
-	pc.Set(SourceFileKey, mdPath)
+	pc.Set(SourceFileKey, srcFile)
 
 	var buf bytes.Buffer
-	if err := md.Convert([]byte(mdText), &buf, parser.WithContext(pc)); err != nil {
+	if err := md.Convert([]byte(src), &buf, parser.WithContext(pc)); err != nil {
 		t.Fatal(err)
 	}
 	return buf.String()
@@ -120,3 +120,150 @@ func TestRelativeLinkResolution(t *testing.T) {
 		t.Fatalf("expected: %s got: %s", expected, out)
 	}
 }
+
+func TestBrokenLinksWarnCallback(t *testing.T) {
+	tmp := t.TempDir()
+	os.Mkdir(filepath.Join(tmp, "content"), 0755)
+
+	warnings := []string{}
+	mr := LinkRewriter{
+		SiteDir:    tmp,
+		ContentDir: tmp + "/content",
+		Warn:       func(msg string) { warnings = append(warnings, msg) },
+	}
+	convertWith(t, mr, "docs/page.md", `[x](nosuch.md)`)
+	expected := []string{"broken link → nosuch.md (resolved as docs/nosuch.md)"}
+	if !slices.Equal(expected, warnings) {
+		t.Fatalf("expected warnings: %q got: %q", expected, warnings)
+	}
+}
+
+func TestRewriteDirectoryLinks(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"content/docs/page.md":        "",
+		"content/docs/pkg/README.md":  "",
+		"content/docs/guide/index.md": "",
+		"content/docs/both/index.md":  "",
+		"content/docs/both/README.md": "",
+		"content/docs/empty/x.png":    "",
+	})
+
+	warnings := []string{}
+	links := []string{}
+	mr := LinkRewriter{
+		SiteDir:    tmp,
+		ContentDir: tmp + "/content",
+		Warn:       func(msg string) { warnings = append(warnings, msg) },
+		Link:       func(destPath, anchor, text string) { links = append(links, destPath) },
+	}
+	out := convertWith(t, mr, "docs/page.md", `[a](pkg/) [b](pkg#usage) [c](guide) [d](./both/) [e](empty/) [f](../docs/pkg/)`)
+	expected := `<p><a href="pkg/README.html">a</a> <a href="pkg/README.html#usage">b</a> <a href="guide/">c</a> <a href="./both/">d</a> <a href="empty/">e</a> <a href="../docs/pkg/README.html">f</a></p>` + "\n"
+	if out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+	expectedLinks := []string{"docs/pkg/README.md", "docs/pkg/README.md", "docs/guide/index.md", "docs/both/index.md", "docs/pkg/README.md"}
+	if !slices.Equal(expectedLinks, links) {
+		t.Fatalf("expected links: %q got: %q", expectedLinks, links)
+	}
+	expectedWarnings := []string{"directory has no index or README → empty/ (resolved as docs/empty)"}
+	if !slices.Equal(expectedWarnings, warnings) {
+		t.Fatalf("expected warnings: %q got: %q", expectedWarnings, warnings)
+	}
+}
+
+func TestRewriteRepoLinks(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"cmd/root.go":          "",
+		"docs/content/page.md": "",
+		"docs/content/x.go":    "",
+	})
+
+	warnings := []string{}
+	mr := LinkRewriter{
+		SiteDir:    tmp + "/docs",
+		ContentDir: tmp + "/docs/content",
+		Warn:       func(msg string) { warnings = append(warnings, msg) },
+		RepoURL:    "https://git.example.com/blob/main/{path}#L{line}",
+		RepoDir:    tmp,
+	}
+	out := convertWith(t, mr, "page.md", `[a](../../cmd/root.go) [b](../../cmd/root.go#L10) [c](../../cmd/root.go#L3-L5) [d](../../cmd/gone.go) [e](x.go) [f](../../../outside.go)`)
+	expected := `<p><a href="https://git.example.com/blob/main/cmd/root.go">a</a>` +
+		` <a href="https://git.example.com/blob/main/cmd/root.go#L10">b</a>` +
+		` <a href="https://git.example.com/blob/main/cmd/root.go#L3-L5">c</a>` +
+		` <a href="https://git.example.com/blob/main/cmd/gone.go">d</a>` +
+		` <a href="x.go">e</a> <a href="../../../outside.go">f</a></p>` + "\n"
+	if out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+	expectedWarnings := []string{
+		"broken link → ../../cmd/gone.go (resolved as cmd/gone.go)",
+		"link outside the repository → ../../../outside.go",
+	}
+	if !slices.Equal(expectedWarnings, warnings) {
+		t.Fatalf("expected warnings: %q got: %q", expectedWarnings, warnings)
+	}
+}
+
+func TestRewriteHTML(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"content/docs/guide.md": "",
+	})
+
+	warnings := []string{}
+	links := []string{}
+	mr := LinkRewriter{
+		SiteDir:    tmp,
+		ContentDir: tmp + "/content",
+		Warn:       func(msg string) { warnings = append(warnings, msg) },
+		Link:       func(destPath, anchor, text string) { links = append(links, destPath+"#"+anchor+" "+text) },
+	}
+
+	// Anything without a .md reference comes back byte for byte.
+	in := "<!DOCTYPE html>\n<P CLASS=x>Text &amp; <a href='other.html'>more</a><br/><!-- c --></P>\n<script>if (a<b) {}</script>"
+	if out := mr.RewriteHTML("docs/page.html", in); out != in {
+		t.Fatalf("output diff:\n%s", strDiff(in, out))
+	}
+
+	in = `<div><A HREF="guide.md#top" class="x"><b>The</b> guide</A> <a href="https://example.com/x.md">ext</a> <a href="gone.md">gone</a><link rel="next" href="/docs/guide.md"/></div>`
+	expected := `<div><a href="guide.html#top" class="x"><b>The</b> guide</A> <a href="https://example.com/x.md">ext</a> <a href="gone.html">gone</a><link rel="next" href="/docs/guide.html"/></div>`
+	if out := mr.RewriteHTML("docs/page.html", in); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+	expectedLinks := []string{"docs/guide.md#top The guide", "docs/gone.md# gone", "docs/guide.md# "}
+	if !slices.Equal(expectedLinks, links) {
+		t.Fatalf("expected links: %q got: %q", expectedLinks, links)
+	}
+	expectedWarnings := []string{"broken link → gone.md (resolved as docs/gone.md)"}
+	if !slices.Equal(expectedWarnings, warnings) {
+		t.Fatalf("expected warnings: %q got: %q", expectedWarnings, warnings)
+	}
+}
+
+func TestRewriteEscapedAndAutoLinks(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"content/my page.md": "",
+	})
+
+	warnings := []string{}
+	mr := LinkRewriter{
+		SiteDir:    tmp,
+		ContentDir: tmp + "/content",
+		Warn:       func(msg string) { warnings = append(warnings, msg) },
+	}
+	in := "[a](my%20page.md#top) [b](gone%20away.md) [c](mailto:me@example.md)\n" +
+		"<https://example.com/d.md> www.example.com/e.md <me@example.md>\n"
+	out := convertWith(t, mr, "page.md", in, goldmark.WithExtensions(extension.Linkify))
+	expected := `<p><a href="my%20page.html#top">a</a> <a href="gone%20away.html">b</a> <a href="mailto:me@example.md">c</a>` + "\n" +
+		`<a href="https://example.com/d.md">https://example.com/d.md</a> <a href="http://www.example.com/e.md">www.example.com/e.md</a> <a href="mailto:me@example.md">me@example.md</a></p>` + "\n"
+	if out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+	expectedWarnings := []string{"broken link → gone%20away.md (resolved as gone away.md)"}
+	if !slices.Equal(expectedWarnings, warnings) {
+		t.Fatalf("expected warnings: %q got: %q", expectedWarnings, warnings)
+	}
+}
@@ -0,0 +1,267 @@
+package build
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// MenuEntry is a node in a menu tree, exposed to templates through .Menus.
+type MenuEntry struct {
+	Identifier string // referenced by the Parent of other entries
+	Title      string
+	URL        string
+	Weight     int
+	Parent     string
+	Page       *Page `json:"-"` // nil for entries declared in site.jsonr
+	Children   Menu
+
+	// Relative to the page being rendered.
+	IsActive       bool
+	HasActiveChild bool
+
+	explicitID bool // Identifier was set rather than derived
+}
+
+// Menu is an ordered list of sibling entries.
+type Menu []*MenuEntry
+
+// menuConfig is a menu entry as written in site.jsonr or in frontmatter.
+type menuConfig struct {
+	Identifier string
+	Title      string
+	URL        string
+	Path       string // older spelling of URL used by the Menu key
+	Weight     int
+	Parent     string
+}
+
+// pageMenu is a page's request to appear in a named menu.
+type pageMenu struct {
+	name  string
+	entry menuConfig
+}
+
+// asPageMenus decodes the Menus frontmatter key. It is either a list of
+// menu names or an object mapping menu names to entry settings:
+//
+//	"Menus": ["main"]
+//	"Menus": {"main": {"Weight": 10, "Parent": "docs"}}
+func asPageMenus(v any) ([]pageMenu, error) {
+	menus := []pageMenu{}
+	switch v := v.(type) {
+	case string:
+		menus = append(menus, pageMenu{name: v})
+	case []any:
+		for _, name := range v {
+			s, ok := name.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected menu name, got %T", name)
+			}
+			menus = append(menus, pageMenu{name: s})
+		}
+	case map[string]any:
+		for name, cfg := range v {
+			entry := menuConfig{}
+			if err := remarshal(cfg, &entry); err != nil {
+				return nil, fmt.Errorf("menu %q: %w", name, err)
+			}
+			menus = append(menus, pageMenu{name: name, entry: entry})
+		}
+		slices.SortFunc(menus, func(a, b pageMenu) int {
+			return cmp.Compare(a.name, b.name)
+		})
+	default:
+		return nil, fmt.Errorf("expected menu names or object, got %T", v)
+	}
+	return menus, nil
+}
+
+// remarshal converts generic JSON data into a typed value.
+func remarshal(in any, out any) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// defaultMenuID derives an identifier from a site-relative URL or a source
+// path without its extension, like "docs/intro" for "/docs/intro.html" or
+// "docs" for "docs/index".
+func defaultMenuID(p string) string {
+	if path.Base(p) == "index" {
+		p = path.Dir(p)
+	}
+	p = strings.Trim(p, "/")
+	if p == "." {
+		return ""
+	}
+	return p
+}
+
+// loadMenus collects the entries of every menu from the Menu and Menus keys
+// of site.jsonr and from page frontmatter. Entries are flat and in
+// declaration order; menusFor turns them into trees.
+func loadMenus(siteConfig map[string]any, pages Pages) (map[string][]*MenuEntry, error) {
+	configs := map[string][]menuConfig{}
+	if v, ok := siteConfig["Menu"]; ok {
+		entries := []menuConfig{}
+		if err := remarshal(v, &entries); err != nil {
+			return nil, fmt.Errorf("site Menu: %w", err)
+		}
+		configs["main"] = entries
+	}
+	if v, ok := siteConfig["Menus"]; ok {
+		named := map[string][]menuConfig{}
+		if err := remarshal(v, &named); err != nil {
+			return nil, fmt.Errorf("site Menus: %w", err)
+		}
+		for name, entries := range named {
+			configs[name] = append(configs[name], entries...)
+		}
+	}
+
+	// main always exists, so templates can range over it without a check.
+	menus := map[string][]*MenuEntry{"main": {}}
+	for name, entries := range configs {
+		for _, cfg := range entries {
+			e := &MenuEntry{
+				Identifier: cfg.Identifier,
+				Title:      cfg.Title,
+				URL:        cmp.Or(cfg.URL, cfg.Path),
+				Weight:     cfg.Weight,
+				Parent:     cfg.Parent,
+				explicitID: cfg.Identifier != "",
+			}
+			if e.Identifier == "" {
+				e.Identifier = cmp.Or(defaultMenuID(strings.TrimSuffix(e.URL, ".html")), e.Title)
+			}
+			menus[name] = append(menus[name], e)
+		}
+	}
+	for _, p := range pages {
+		for _, pm := range p.menus {
+			title := cmp.Or(pm.entry.Title, p.Title)
+			menus[pm.name] = append(menus[pm.name], &MenuEntry{
+				Identifier: cmp.Or(pm.entry.Identifier, defaultMenuID(strings.TrimSuffix(p.File.Path, p.File.Ext)), title),
+				Title:      title,
+				URL:        p.URL,
+				Weight:     pm.entry.Weight,
+				Parent:     pm.entry.Parent,
+				Page:       p,
+				explicitID: pm.entry.Identifier != "",
+			})
+		}
+	}
+
+	for name, entries := range menus {
+		if err := checkMenu(entries); err != nil {
+			return nil, fmt.Errorf("menu %q: %w", name, err)
+		}
+	}
+	return menus, nil
+}
+
+// checkMenu makes sure identifiers are unique and every parent exists
+// without forming a cycle. Only identifiers that were set can clash; a
+// derived one that is taken gets a numeric suffix, like "Blog-2".
+func checkMenu(entries []*MenuEntry) error {
+	byID := map[string]*MenuEntry{}
+	for _, e := range entries {
+		if !e.explicitID {
+			continue
+		}
+		if _, ok := byID[e.Identifier]; ok {
+			return fmt.Errorf("duplicate identifier %q", e.Identifier)
+		}
+		byID[e.Identifier] = e
+	}
+	for _, e := range entries {
+		if e.explicitID {
+			continue
+		}
+		id := e.Identifier
+		for n := 2; byID[id] != nil; n++ {
+			id = fmt.Sprintf("%s-%d", e.Identifier, n)
+		}
+		e.Identifier = id
+		byID[id] = e
+	}
+	for _, e := range entries {
+		seen := map[string]bool{e.Identifier: true}
+		for p := e.Parent; p != ""; p = byID[p].Parent {
+			if _, ok := byID[p]; !ok {
+				return fmt.Errorf("parent %q of %q not found", p, e.Identifier)
+			}
+			if seen[p] {
+				return fmt.Errorf("parent cycle at %q", e.Identifier)
+			}
+			seen[p] = true
+		}
+	}
+	return nil
+}
+
+// menusFor builds the menu trees as seen from page.
+func (s *site) menusFor(page *Page) map[string]Menu {
+	out := map[string]Menu{}
+	for name, entries := range s.menus {
+		byID := map[string]*MenuEntry{}
+		for _, e := range entries {
+			c := *e
+			c.Children = nil
+			byID[e.Identifier] = &c
+		}
+		roots := Menu{}
+		for _, e := range entries {
+			c := byID[e.Identifier]
+			if e.Parent == "" {
+				roots = append(roots, c)
+			} else {
+				parent := byID[e.Parent]
+				parent.Children = append(parent.Children, c)
+			}
+		}
+		roots.sort()
+		roots.markActive(page)
+		out[name] = roots
+	}
+	return out
+}
+
+// sort orders entries by weight, keeping declaration order for ties.
+func (m Menu) sort() {
+	slices.SortStableFunc(m, func(a, b *MenuEntry) int {
+		return cmp.Compare(a.Weight, b.Weight)
+	})
+	for _, e := range m {
+		e.Children.sort()
+	}
+}
+
+// markActive sets IsActive and HasActiveChild and reports whether any entry
+// in m is active.
+func (m Menu) markActive(page *Page) bool {
+	active := false
+	for _, e := range m {
+		e.IsActive = e.Page == page || (e.Page == nil && page != nil && sameURL(e.URL, page.URL))
+		e.HasActiveChild = e.Children.markActive(page)
+		active = active || e.IsActive || e.HasActiveChild
+	}
+	return active
+}
+
+// sameURL compares site-relative URLs, treating "/dir/index.html" as "/dir/".
+func sameURL(a, b string) bool {
+	norm := func(u string) string {
+		if path.Base(u) == "index.html" {
+			return strings.TrimSuffix(u, "index.html")
+		}
+		return u
+	}
+	return a != "" && norm(a) == norm(b)
+}
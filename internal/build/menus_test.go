@@ -0,0 +1,121 @@
+package build
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// menuString renders a menu tree compactly, marking active entries with *
+// and entries with active children with +.
+func menuString(m Menu) string {
+	parts := []string{}
+	for _, e := range m {
+		s := e.Title
+		if e.IsActive {
+			s += "*"
+		}
+		if e.HasActiveChild {
+			s += "+"
+		}
+		if len(e.Children) > 0 {
+			s += "(" + menuString(e.Children) + ")"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestMenus(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr": `{
+  "Menu": [
+    {"Title": "Home", "Path": "/"},
+    {"Title": "Blog", "Path": "/blog/", "Weight": 20},
+  ],
+  "Menus": {
+    "footer": [{"Title": "Contact", "URL": "mailto:x@example.com"}],
+  },
+}`,
+		"templates/base.html":   `{{.Content}}`,
+		"content/index.md":      "home",
+		"content/docs/index.md": "---\n{\"Title\": \"Docs\", \"Menus\": {\"main\": {\"Weight\": 10}}}\n---\n",
+		"content/docs/b.md":     "---\n{\"Title\": \"B\", \"Menus\": {\"main\": {\"Parent\": \"docs\", \"Weight\": 2}}}\n---\n",
+		"content/docs/a.md":     "---\n{\"Title\": \"A\", \"Menus\": {\"main\": {\"Parent\": \"docs\", \"Weight\": 1, \"Title\": \"Alpha\"}, \"footer\": {}}}\n---\n",
+		"content/about.md":      "---\n{\"Title\": \"About\", \"Menus\": [\"main\"]}\n---\n",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir: tmp,
+	}}
+	siteConfig, err := readSiteConfig(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	site, err := loadSite(opts, siteConfig, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]string{
+		"index.md":  "Home* About Docs(Alpha B) Blog",
+		"docs/b.md": "Home About Docs+(Alpha B*) Blog",
+		"about.md":  "Home About* Docs(Alpha B) Blog",
+	} {
+		menus := site.menusFor(site.byPath[filepath.FromSlash(path)])
+		if out := menuString(menus["main"]); out != expected {
+			t.Errorf("%s: expected %q got %q", path, expected, out)
+		}
+	}
+
+	menus := site.menusFor(site.byPath[filepath.FromSlash("docs/a.md")])
+	if out := menuString(menus["footer"]); out != "Contact A*" {
+		t.Errorf("unexpected footer menu: %q", out)
+	}
+}
+
+func TestMenuErrors(t *testing.T) {
+	for config, expected := range map[string]string{
+		`{"Menu": [{"Title": "A", "Parent": "nosuch"}]}`:                                   `menu "main": parent "nosuch" of "A" not found`,
+		`{"Menu": [{"Identifier": "a", "Title": "A"}, {"Identifier": "a", "Title": "B"}]}`: `menu "main": duplicate identifier "a"`,
+		`{"Menu": [{"Title": "A", "Parent": "B"}, {"Title": "B", "Parent": "A"}]}`:         `menu "main": parent cycle at "A"`,
+	} {
+		siteConfig := map[string]any{}
+		if err := json.Unmarshal([]byte(config), &siteConfig); err != nil {
+			t.Fatal(err)
+		}
+		_, err := loadMenus(siteConfig, nil)
+		if err == nil || err.Error() != expected {
+			t.Errorf("expected %q got %v", expected, err)
+		}
+	}
+}
+
+func TestMenuDerivedIdentifiers(t *testing.T) {
+	siteConfig := map[string]any{}
+	config := `{"Menus": {"footer": [
+  {"Title": "Blog", "URL": "/blog/"},
+  {"Title": "Blog", "URL": "/blog/"},
+  {"Title": "Old", "URL": "/old.html", "Parent": "blog"},
+  {"Identifier": "blog-2", "Title": "Set"}
+]}}`
+	if err := json.Unmarshal([]byte(config), &siteConfig); err != nil {
+		t.Fatal(err)
+	}
+	menus, err := loadMenus(siteConfig, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, e := range menus["footer"] {
+		ids = append(ids, e.Identifier)
+	}
+	if out, expected := strings.Join(ids, " "), "blog blog-3 old blog-2"; out != expected {
+		t.Errorf("expected identifiers %q got %q", expected, out)
+	}
+	if main, ok := menus["main"]; !ok || len(main) != 0 {
+		t.Errorf("expected an empty main menu, got %v", main)
+	}
+}
@@ -0,0 +1,87 @@
+package build
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// PageMetaTransformer fills in a page's Title from its first top-level H1
+// and its Description from its first paragraph of text, when frontmatter
+// doesn't set them. It must run before the TOC is collected so a stripped
+// heading doesn't appear there.
+type PageMetaTransformer struct {
+	Page        *Page
+	Title       bool
+	StripTitle  bool // remove the H1 used as the title from the body
+	Description bool
+}
+
+func (t PageMetaTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+
+	var h1 *ast.Heading
+	description := ""
+	for n := node.FirstChild(); n != nil; n = n.NextSibling() {
+		switch n := n.(type) {
+		case *ast.Heading:
+			if h1 == nil && n.Level == 1 {
+				h1 = n
+			}
+		case *ast.Paragraph:
+			if description == "" {
+				description = strings.Join(strings.Fields(proseText(n, source)), " ")
+			}
+		}
+	}
+
+	if t.Title && (t.Page.Title == "" || t.Page.titleFromHeading) && h1 != nil {
+		t.Page.Title = strings.Join(strings.Fields(nodeText(h1, source)), " ")
+		if t.StripTitle {
+			node.RemoveChild(node, h1)
+		}
+	}
+	if t.Description && t.Page.Description == "" {
+		t.Page.Description = description
+	}
+}
+
+// proseText is like nodeText but leaves out image alt text, so a paragraph
+// of badges has no text.
+func proseText(n ast.Node, source []byte) string {
+	b := strings.Builder{}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if _, ok := c.(*ast.Image); ok {
+			continue
+		}
+		if c.HasChildren() {
+			b.WriteString(proseText(c, source))
+		} else {
+			b.WriteString(nodeText(c, source))
+		}
+	}
+	return b.String()
+}
+
+// deriveTitle sets the Title of a Markdown page without one from its first
+// top-level H1, like PageMetaTransformer, so that it is known before any page
+// is converted. PageMetaTransformer still strips the heading.
+func deriveTitle(page *Page, opts *Options) {
+	if page.Title != "" || strings.ToLower(filepath.Ext(page.path)) != ".md" {
+		return
+	}
+	exts, parserOpts, _ := opts.Markdown(page).goldmarkOptions()
+	md := goldmark.New(goldmark.WithExtensions(exts...), goldmark.WithParserOptions(parserOpts...))
+	doc := md.Parser().Parse(text.NewReader(page.Body))
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		if h, ok := n.(*ast.Heading); ok && h.Level == 1 {
+			page.Title = strings.Join(strings.Fields(nodeText(h, page.Body)), " ")
+			page.titleFromHeading = true
+			return
+		}
+	}
+}
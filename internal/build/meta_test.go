@@ -0,0 +1,89 @@
+package build
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/util"
+)
+
+func TestRenderContentDerivedMeta(t *testing.T) {
+	siteTest{
+		files: map[string]string{
+			"templates/base.html": `{{.Page.Title}}|{{.Page.Description}}|{{len .Page.TOCItems}}|{{.Content}}`,
+			"content/readme.md":   "[![badge](b.svg)](x)\n\n# The *Readme* -- guide\n\nFirst `para`\nwraps.\n\n## Usage\n\nMore.\n",
+			"content/titled.md":   "---\n{\"Title\": \"Set\", \"Description\": \"Kept\"}\n---\n# Heading\n\nText.\n",
+			"content/nested.md":   "> # Quoted\n\n## Only H2\n",
+		},
+		opts: RawOptions{
+			DeriveTitle:       true,
+			StripTitleHeading: true,
+			DeriveDescription: true,
+		},
+		output: map[string]string{
+			"readme.html": "The Readme – guide|First para wraps.|1|" +
+				`<p><a href="x"><img src="b.svg" alt="badge"></a></p>` + "\n" +
+				`<p>First <code>para</code>` + "\nwraps.</p>\n" +
+				`<h2 id="usage">Usage</h2>` + "\n<p>More.</p>\n",
+			"titled.html": "Set|Kept|1|" + `<h1 id="heading">Heading</h1>` + "\n<p>Text.</p>\n",
+			"nested.html": "||2|" + "<blockquote>\n" + `<h1 id="quoted">Quoted</h1>` + "\n</blockquote>\n" + `<h2 id="only-h2">Only H2</h2>` + "\n",
+		},
+	}.run(t)
+}
+
+func TestPageMetaTransformer(t *testing.T) {
+	for name, tc := range map[string]struct {
+		page        Page
+		transformer PageMetaTransformer
+		body        string
+		title       string
+		description string
+		content     string
+	}{
+		"derive": {
+			transformer: PageMetaTransformer{Title: true, Description: true},
+			body:        "![badge](b.svg)\n\n# First\n\nSome *text*.\n\n# Second\n",
+			title:       "First",
+			description: "Some text.",
+			content:     "<p><img src=\"b.svg\" alt=\"badge\"></p>\n<h1>First</h1>\n<p>Some <em>text</em>.</p>\n<h1>Second</h1>\n",
+		},
+		"strip": {
+			transformer: PageMetaTransformer{Title: true, StripTitle: true},
+			body:        "# First\n\nText.\n",
+			title:       "First",
+			content:     "<p>Text.</p>\n",
+		},
+		"frontmatter": {
+			page:        Page{Title: "Set", Description: "Kept"},
+			transformer: PageMetaTransformer{Title: true, StripTitle: true, Description: true},
+			body:        "# First\n\nText.\n",
+			title:       "Set",
+			description: "Kept",
+			content:     "<h1>First</h1>\n<p>Text.</p>\n",
+		},
+		"off": {
+			body:    "# First\n\nText.\n",
+			content: "<h1>First</h1>\n<p>Text.</p>\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			page := tc.page
+			tc.transformer.Page = &page
+			md := goldmark.New(goldmark.WithParserOptions(
+				parser.WithASTTransformers(util.Prioritized(tc.transformer, 100)),
+			))
+			buf := &bytes.Buffer{}
+			if err := md.Convert([]byte(tc.body), buf); err != nil {
+				t.Fatal(err)
+			}
+			if page.Title != tc.title || page.Description != tc.description {
+				t.Errorf("expected title %q and description %q, got %q and %q", tc.title, tc.description, page.Title, page.Description)
+			}
+			if buf.String() != tc.content {
+				t.Errorf("output diff:\n%s", strDiff(tc.content, buf.String()))
+			}
+		})
+	}
+}
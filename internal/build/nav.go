@@ -0,0 +1,134 @@
+package build
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"slices"
+)
+
+// SectionConfig controls how Prev and Next are set for the pages of a
+// section. It is read from the Sections key of yugo.jsonr, where "*" applies
+// to sections without their own entry and "" is the content root.
+type SectionConfig struct {
+	// Order is "weight" (the default) which orders by Weight, then Date,
+	// then Title, or "date" which orders by Date, then Title, or "title".
+	Order   string
+	Reverse bool
+	// Flatten orders the pages of nested directories as one sequence, with
+	// each directory's index page leading its own pages. Otherwise only
+	// pages in the same directory are linked, and index pages are left out.
+	Flatten bool
+}
+
+func (sc SectionConfig) check() error {
+	switch sc.Order {
+	case "", "weight", "date", "title":
+		return nil
+	}
+	return fmt.Errorf("unknown Order %q", sc.Order)
+}
+
+// compare orders two pages according to the section config, falling back
+// to the source path.
+func (sc SectionConfig) compare(a, b *Page) int {
+	c := 0
+	switch sc.Order {
+	case "date":
+		c = cmp.Or(a.Date.Compare(b.Date), cmp.Compare(a.LinkTitle(), b.LinkTitle()))
+	case "title":
+		c = cmp.Compare(a.LinkTitle(), b.LinkTitle())
+	default:
+		c = cmp.Or(cmp.Compare(a.Weight, b.Weight), a.Date.Compare(b.Date), cmp.Compare(a.LinkTitle(), b.LinkTitle()))
+	}
+	c = cmp.Or(c, cmp.Compare(a.File.Path, b.File.Path))
+	if sc.Reverse {
+		c = -c
+	}
+	return c
+}
+
+// setPrevNext links the pages of every section in order.
+func (s *site) setPrevNext(opts *Options) {
+	// Pages by directory, without index pages, and the subdirectories that
+	// contain pages.
+	byDir := map[string]Pages{}
+	subdirs := map[string][]string{}
+	for _, p := range s.pages {
+		dir := path.Dir(p.File.Path)
+		if !p.isIndex() {
+			byDir[dir] = append(byDir[dir], p)
+		}
+		for dir != "." {
+			parent := path.Dir(dir)
+			if !slices.Contains(subdirs[parent], dir) {
+				subdirs[parent] = append(subdirs[parent], dir)
+			}
+			dir = parent
+		}
+	}
+
+	link := func(seq Pages) {
+		for i, p := range seq {
+			if i > 0 {
+				p.Prev = seq[i-1]
+			}
+			if i < len(seq)-1 {
+				p.Next = seq[i+1]
+			}
+		}
+	}
+
+	// flatten orders a directory's pages and subdirectories together.
+	var flatten func(dir string, sc SectionConfig) Pages
+	flatten = func(dir string, sc SectionConfig) Pages {
+		type item struct {
+			page *Page
+			dir  string // set for subdirectories
+		}
+		items := []item{}
+		for _, p := range byDir[dir] {
+			items = append(items, item{page: p})
+		}
+		for _, sub := range subdirs[dir] {
+			items = append(items, item{page: s.dirPage(sub), dir: sub})
+		}
+		slices.SortStableFunc(items, func(a, b item) int {
+			return cmp.Or(sc.compare(a.page, b.page), cmp.Compare(a.dir, b.dir))
+		})
+
+		seq := Pages{}
+		for _, it := range items {
+			if it.dir == "" {
+				seq = append(seq, it.page)
+				continue
+			}
+			if it.page.relPath != "" {
+				seq = append(seq, it.page)
+			}
+			seq = append(seq, flatten(it.dir, sc)...)
+		}
+		return seq
+	}
+
+	// Root pages are their own section, and each top-level directory is a
+	// section.
+	link(byDir["."].sorted(opts.SectionConfig("").compare))
+	for _, section := range subdirs["."] {
+		sc := opts.SectionConfig(section)
+		if sc.Flatten {
+			seq := Pages{}
+			if index := s.dirPage(section); index.relPath != "" {
+				seq = append(seq, index)
+			}
+			link(append(seq, flatten(section, sc)...))
+			continue
+		}
+		dirs := []string{section}
+		for len(dirs) > 0 {
+			dir := dirs[0]
+			dirs = append(dirs[1:], subdirs[dir]...)
+			link(byDir[dir].sorted(sc.compare))
+		}
+	}
+}
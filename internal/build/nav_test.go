@@ -0,0 +1,123 @@
+package build
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderContentPrevNext(t *testing.T) {
+	siteTest{
+		files: map[string]string{
+			"templates/base.html":   `{{with .Page.Prev}}{{.Title}}{{end}}[{{.Page.Title}}]{{with .Page.Next}}{{.Title}}{{end}}`,
+			"content/docs/index.md": "---\n{\"Title\": \"Docs\"}\n---\n",
+			"content/docs/b.md":     "---\n{\"Title\": \"B\", \"Weight\": 1}\n---\n",
+			"content/docs/a.md":     "---\n{\"Title\": \"A\", \"Weight\": 2}\n---\n",
+			"content/blog/old.md":   "---\n{\"Title\": \"Old\", \"Date\": \"2024-01-01\"}\n---\n",
+			"content/blog/new.md":   "---\n{\"Title\": \"New\", \"Date\": \"2024-02-01\"}\n---\n",
+		},
+		opts: RawOptions{
+			Sections: map[string]SectionConfig{
+				"blog": {Order: "date", Reverse: true},
+			},
+		},
+		output: map[string]string{
+			"docs/index.html": "[Docs]",
+			"docs/b.html":     "[B]A",
+			"docs/a.html":     "B[A]",
+			"blog/new.html":   "[New]Old",
+			"blog/old.html":   "New[Old]",
+		},
+	}.run(t)
+}
+
+// navPage is a page with the fields that order it in a section.
+func navPage(relPath, title string, weight int, date string) *Page {
+	p := &Page{Title: title, Weight: weight, Params: map[string]any{}}
+	if date != "" {
+		p.Date, _ = time.Parse(time.DateOnly, date)
+	}
+	p.relPath = relPath
+	p.outPath = strings.TrimSuffix(relPath, ".md") + ".html"
+	p.setLocation(&url.URL{})
+	return p
+}
+
+func TestSetPrevNext(t *testing.T) {
+	pages := Pages{
+		navPage("about.md", "About", 0, ""),
+		navPage("contact.md", "Contact", 0, ""),
+		// Default order: Weight, then Date, then Title.
+		navPage("docs/index.md", "Docs", 0, ""),
+		navPage("docs/b.md", "B", 1, ""),
+		navPage("docs/a.md", "A", 2, ""),
+		navPage("docs/c.md", "C", 2, ""),
+		navPage("docs/sub/d.md", "D", 0, ""),
+		// Newest first.
+		navPage("blog/old.md", "Old", 0, "2024-01-01"),
+		navPage("blog/new.md", "New", 0, "2024-02-01"),
+		// Chapters flattened into one sequence.
+		navPage("guide/index.md", "Guide", 0, ""),
+		navPage("guide/intro.md", "Intro", 1, ""),
+		navPage("guide/two/index.md", "Two", 3, ""),
+		navPage("guide/two/step.md", "Step", 0, ""),
+		navPage("guide/one/index.md", "One", 2, ""),
+		navPage("guide/one/setup.md", "Setup", 0, ""),
+		navPage("guide/one/install.md", "Install", -1, ""),
+		// A chapter without an index page only contributes its pages, and
+		// sorts like an untitled page with no weight.
+		navPage("guide/zz/extra.md", "Extra", 0, ""),
+	}
+	opts := &Options{&RawOptions{
+		Sections: map[string]SectionConfig{
+			"blog":  {Order: "date", Reverse: true},
+			"guide": {Flatten: true},
+		},
+	}}
+	newSite(nil, pages).setPrevNext(opts)
+
+	out := []string{}
+	for _, p := range pages {
+		s := "[" + p.Title + "]"
+		if p.Prev != nil {
+			s = p.Prev.Title + s
+		}
+		if p.Next != nil {
+			s += p.Next.Title
+		}
+		out = append(out, s)
+	}
+	expected := []string{
+		"[About]Contact", "About[Contact]",
+		"[Docs]", "[B]A", "B[A]C", "A[C]", "[D]",
+		"New[Old]", "[New]Old",
+		"[Guide]Extra", "Extra[Intro]One", "Setup[Two]Step", "Two[Step]",
+		"Intro[One]Install", "Install[Setup]Two", "One[Install]Setup", "Guide[Extra]Intro",
+	}
+	if strings.Join(out, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected:\n%s\ngot:\n%s", strings.Join(expected, " "), strings.Join(out, " "))
+	}
+}
+
+func TestSectionConfigCompare(t *testing.T) {
+	a := navPage("a.md", "Beta", 1, "2024-02-01")
+	b := navPage("b.md", "Alpha", 2, "2024-01-01")
+	c := navPage("c.md", "Alpha", 2, "2024-01-01")
+	for _, tc := range []struct {
+		sc       SectionConfig
+		x, y     *Page
+		expected int
+	}{
+		{SectionConfig{}, a, b, -1},
+		{SectionConfig{Order: "date"}, a, b, 1},
+		{SectionConfig{Order: "title"}, a, b, 1},
+		{SectionConfig{Order: "title", Reverse: true}, a, b, -1},
+		// Equal pages fall back to the source path.
+		{SectionConfig{}, b, c, -1},
+	} {
+		if c := tc.sc.compare(tc.x, tc.y); c != tc.expected {
+			t.Errorf("%+v: compare(%s, %s) expected %d, got %d", tc.sc, tc.x.relPath, tc.y.relPath, tc.expected, c)
+		}
+	}
+}
@@ -3,22 +3,149 @@ package build
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/msolo/jsonr"
 )
 
+// Page is a content file as exposed to templates as .Page.
+//
+// Well-known frontmatter keys are decoded into typed fields. Any other
+// top-level keys are available in Params.
 type Page struct {
-	Params map[string]any
-	Body   []byte // content without frontmatter
+	Title       string
+	Description string
+	Date        time.Time
+	LastMod     time.Time // defaults to Date
+	Weight      int
+	Draft       bool
+	Slug        string   // replaces the basename of the output file
+	Layout      string   // template used instead of the base template
+	Aliases     []string // other names for wiki links, see WikiIndex
+	Params      map[string]any
+
+	// Where the page lives. URL is the path from the site root, with a
+	// trailing index.html dropped, so the home page is "/". RelPermalink adds
+	// the path of the BaseURL, and Permalink is the full BaseURL.
+	URL          string
+	RelPermalink string
+	Permalink    string
+	Section      string // top-level content directory, "" for the root
+	File         FileInfo
+
+	// The index pages of the directories above this page, starting at the
+	// root. Parent is the last of them.
+	Ancestors Pages `json:"-"`
+	Parent    *Page `json:"-"`
+
+	// Neighbours within the section, ordered as set in Sections.
+	Prev *Page `json:"-"`
+	Next *Page `json:"-"`
+
+	// Internal Markdown links between published pages, in source order.
+	OutLinks  Pages      `json:"-"`
+	Backlinks []Backlink `json:"-"`
+
+	// Other pages sharing the most frontmatter terms, see RelatedConfig.
+	Related Pages `json:"-"`
+
+	// Files next to an index page, see Resource.
+	Resources Resources
+
+	TOC      template.HTML
+	TOCItems []TOCItem
+
+	// Computed when the body is rendered.
+	Content     template.HTML `json:"-"`
+	Summary     template.HTML
+	Truncated   bool   // Summary is shorter than Content
+	PlainText   string `json:"-"`
+	WordCount   int
+	ReadingTime int // minutes
+
+	Body []byte `json:"-"` // content without frontmatter
+
+	frontmatter map[string]any // as written, for validation
+	warnings    []string       // problems found while rendering the body
+	menus       []pageMenu     // from the Menus key
+	links       []pageLink     // internal links found while rendering the body
+	includes    []string       // absolute paths of files included by the body
+
+	resourceMetas []resourceMeta // from the Resources key
+	markdown      MarkdownConfig // from the Markdown key
+
+	bodyLine int    // lines before Body in the source file, for errors
+	dirTitle string // directory name of an index page, see LinkTitle
+
+	titleFromHeading bool // Title was derived from the first H1, see deriveTitle
+
+	path    string // path of the source file
+	relPath string // source path relative to ContentDir
+	outPath string // output path relative to OutDir
+}
+
+// FileInfo describes the source file of a page.
+type FileInfo struct {
+	Path     string // relative to the content directory, like "docs/intro.md"
+	Dir      string // like "docs/", or "" at the root
+	BaseName string // without extension, like "intro"
+	Ext      string // like ".md"
 }
 
-// Extracts JSONR frontmatter iff the file begins with '---'
-func ParsePage(src []byte) (Page, error) {
+func newFileInfo(relPath string) FileInfo {
+	p := filepath.ToSlash(relPath)
+	dir, name := path.Split(p)
+	ext := path.Ext(name)
+	return FileInfo{
+		Path:     p,
+		Dir:      dir,
+		BaseName: strings.TrimSuffix(name, ext),
+		Ext:      ext,
+	}
+}
+
+// setLocation fills in the URLs and source file details from the page's
+// paths.
+func (p *Page) setLocation(baseURL *url.URL) {
+	urlPath := "/" + filepath.ToSlash(p.outPath)
+	if path.Base(urlPath) == "index.html" {
+		urlPath = strings.TrimSuffix(urlPath, "index.html")
+	}
+	p.URL, p.RelPermalink, p.Permalink = siteURLs(baseURL, urlPath)
+
+	p.Section = sectionOf(p.relPath)
+	p.File = newFileInfo(p.relPath)
+	if p.isIndex() {
+		p.dirTitle = dirTitle(path.Dir(p.File.Path))
+	}
+}
+
+// siteURLs escapes a path from the site root and places it under baseURL.
+func siteURLs(baseURL *url.URL, urlPath string) (u, relPermalink, permalink string) {
+	u = (&url.URL{Path: urlPath}).EscapedPath()
+	rel := strings.TrimSuffix(baseURL.Path, "/") + urlPath
+	relPermalink = (&url.URL{Path: rel}).EscapedPath()
+	permalink = (&url.URL{Scheme: baseURL.Scheme, Host: baseURL.Host, Path: rel}).String()
+	return u, relPermalink, permalink
+}
+
+// Extracts JSONR frontmatter iff the file begins with '---'. Dates without
+// an explicit zone are interpreted in loc.
+func ParsePage(src []byte, loc *time.Location) (Page, error) {
 	trimmed := bytes.TrimSpace(src)
 	const delim = "---\n"
 	if !bytes.HasPrefix(trimmed, []byte(delim)) {
 		// No frontmatter
-		return Page{Params: map[string]any{}, Body: src}, nil
+		return Page{Params: map[string]any{}, Body: src, frontmatter: map[string]any{}}, nil
 	}
 	trimmed = trimmed[len(delim):]
 
@@ -45,20 +172,178 @@ loop:
 	fmText := trimmed[:end+1]
 	rest := trimmed[end+1:]
 
-	// Look for a frontmatter separator --- on its own line
-	if !bytes.HasPrefix(bytes.TrimSpace(rest), []byte(delim)) {
+	// Look for a frontmatter separator --- on its own line. It may also end
+	// the file if there is no body.
+	rest = bytes.TrimLeft(rest, " \t\r\n")
+	switch {
+	case bytes.Equal(rest, []byte("---")):
+		rest = nil
+	case bytes.HasPrefix(rest, []byte(delim)):
+		rest = rest[len(delim):]
+	case bytes.HasPrefix(rest, []byte("---\r\n")):
+		rest = rest[len("---\r\n"):]
+	default:
 		return Page{}, errors.New("missing --- after JSONR frontmatter")
 	}
 
-	rest = rest[len(delim):]
+	frontmatter := map[string]any{}
+	if err := jsonr.Unmarshal(fmText, &frontmatter); err != nil {
+		return Page{}, err
+	}
 
-	params := map[string]any{}
-	if err := jsonr.Unmarshal(fmText, &params); err != nil {
+	page := Page{Body: []byte(rest), frontmatter: frontmatter}
+	if rest != nil {
+		// rest is a slice of src, so the difference in capacity is its offset.
+		page.bodyLine = bytes.Count(src[:cap(src)-cap(rest)], []byte("\n"))
+	}
+	if err := page.setFrontmatter(frontmatter, loc); err != nil {
 		return Page{}, err
 	}
+	return page, nil
+}
 
-	return Page{
-		Params: params,
-		Body:   []byte(rest),
-	}, nil
+// setFrontmatter decodes the well-known keys into their fields and leaves
+// everything else in Params.
+func (p *Page) setFrontmatter(fm map[string]any, loc *time.Location) error {
+	p.Params = map[string]any{}
+	for k, v := range fm {
+		var err error
+		switch k {
+		case "Title":
+			p.Title, err = asString(v)
+		case "Description":
+			p.Description, err = asString(v)
+		case "Date":
+			p.Date, err = asTime(v, loc)
+		case "LastMod":
+			p.LastMod, err = asTime(v, loc)
+		case "Weight":
+			p.Weight, err = asInt(v)
+		case "Draft":
+			p.Draft, err = asBool(v)
+		case "Slug":
+			p.Slug, err = asString(v)
+		case "Layout":
+			p.Layout, err = asString(v)
+		case "Aliases":
+			p.Aliases, err = asStrings(v)
+		case "Menus":
+			p.menus, err = asPageMenus(v)
+		case "Resources":
+			p.resourceMetas, err = asResourceMetas(v)
+		case "Markdown":
+			p.markdown, err = asMarkdownConfig(v)
+		case "Summary":
+			var summary string
+			summary, err = asString(v)
+			p.Summary = template.HTML(template.HTMLEscapeString(summary))
+		default:
+			p.Params[k] = v
+		}
+		if err != nil {
+			return fmt.Errorf("frontmatter key %q: %w", k, err)
+		}
+	}
+	if p.LastMod.IsZero() {
+		p.LastMod = p.Date
+	}
+	return nil
+}
+
+// missingPageField matches the template error for a field Page doesn't have.
+// .Page itself is reached through the template data map, so its type is only
+// known for pages reached through fields like .Page.Parent.
+var missingPageField = regexp.MustCompile(`at <(?:\$?\.Page\.(\w+)>: can't evaluate field \w+ in type interface \{\}|[^>]*\.(\w+)>: can't evaluate field \w+ in type \*build\.Page)`)
+
+// explainParams adds a hint to a template error from a lookup like
+// {{ .Page.Author }}, which worked when .Page was the frontmatter map. Keys
+// without a field of their own are in Params now.
+func explainParams(err error) error {
+	m := missingPageField.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+	key := m[1] + m[2]
+	return fmt.Errorf("%w; frontmatter key %q has no field on .Page, use .Page.Params.%s", err, key, key)
+}
+
+// warn records a problem with the page and reports it on stderr.
+func (p *Page) warn(msg string) {
+	fmt.Fprintf(os.Stderr, "WARN: %s: %s\n", p.relPath, msg)
+	p.warnings = append(p.warnings, msg)
+}
+
+func asString(v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("expected string, got %T", v)
+	}
+	return s, nil
+}
+
+func asBool(v any) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected bool, got %T", v)
+	}
+	return b, nil
+}
+
+func asStrings(v any) ([]string, error) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected array of strings, got %T", v)
+	}
+	strs := make([]string, len(list))
+	for i, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected array of strings, got %T element", item)
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+func asInt(v any) (int, error) {
+	f, ok := v.(float64)
+	if !ok || f != math.Trunc(f) {
+		return 0, fmt.Errorf("expected integer, got %v", v)
+	}
+	return int(f), nil
+}
+
+func asTime(v any, loc *time.Location) (time.Time, error) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected date string, got %T", v)
+	}
+	return parseDate(s, loc)
+}
+
+// Date layouts accepted in frontmatter, most specific first.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+}
+
+// parseDate parses s with the first matching layout. Layouts without a zone
+// are interpreted in loc.
+func parseDate(s string, loc *time.Location) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", s)
 }
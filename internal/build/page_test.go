@@ -0,0 +1,146 @@
+package build
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePageFrontmatter(t *testing.T) {
+	src := `---
+{
+  "Title": "Hello",
+  "Description": "A page",
+  "Date": "2024-03-01 09:30",
+  "Weight": 3,
+  "Draft": true,
+  "Slug": "hi",
+  "Layout": "post.html",
+  "Titel": "typo",
+}
+---
+body`
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := ParsePage([]byte(src), loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if page.Title != "Hello" || page.Description != "A page" || page.Slug != "hi" || page.Layout != "post.html" {
+		t.Fatalf("unexpected string fields: %+v", page)
+	}
+	if page.Weight != 3 || !page.Draft {
+		t.Fatalf("unexpected weight/draft: %+v", page)
+	}
+	expectedDate := time.Date(2024, 3, 1, 9, 30, 0, 0, loc)
+	if !page.Date.Equal(expectedDate) || page.Date.Location() != loc {
+		t.Fatalf("expected date %s got %s", expectedDate, page.Date)
+	}
+	if !page.LastMod.Equal(page.Date) {
+		t.Fatalf("expected LastMod to default to Date, got %s", page.LastMod)
+	}
+	if page.Params["Titel"] != "typo" || len(page.Params) != 1 {
+		t.Fatalf("expected unknown keys in Params: %v", page.Params)
+	}
+	if strings.TrimSpace(string(page.Body)) != "body" {
+		t.Fatalf("unexpected body: %q", page.Body)
+	}
+}
+
+func TestParsePageDateFormats(t *testing.T) {
+	for _, s := range []string{
+		"2024-03-01",
+		"2024-03-01T00:00:00Z",
+		"2024-03-01T00:00:00",
+		"March 1, 2024",
+		"Mar 1, 2024",
+		"1 Mar 2024",
+	} {
+		d, err := parseDate(s, time.UTC)
+		if err != nil {
+			t.Errorf("%s: %s", s, err)
+			continue
+		}
+		if !d.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: parsed as %s", s, d)
+		}
+	}
+}
+
+func TestParsePageFrontmatterErrors(t *testing.T) {
+	for src, expected := range map[string]string{
+		"---\n{\"Date\": \"next tuesday\"}\n---\nbody": `frontmatter key "Date": unrecognized date "next tuesday"`,
+		"---\n{\"Weight\": 1.5}\n---\nbody":            `frontmatter key "Weight": expected integer, got 1.5`,
+		"---\n{\"Title\": 7}\n---\nbody":               `frontmatter key "Title": expected string, got float64`,
+	} {
+		_, err := ParsePage([]byte(src), time.UTC)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestPageLocation(t *testing.T) {
+	base, err := url.Parse("https://example.com/blog/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		relPath, outPath, url, relPermalink, permalink, section string
+		file                                                    FileInfo
+	}{
+		{"index.md", "index.html", "/", "/blog/", "https://example.com/blog/", "",
+			FileInfo{Path: "index.md", Dir: "", BaseName: "index", Ext: ".md"}},
+		{"docs/a b.html", "docs/a b.html", "/docs/a%20b.html", "/blog/docs/a%20b.html", "https://example.com/blog/docs/a%20b.html", "docs",
+			FileInfo{Path: "docs/a b.html", Dir: "docs/", BaseName: "a b", Ext: ".html"}},
+		{"docs/api/index.md", "docs/api/index.html", "/docs/api/", "/blog/docs/api/", "https://example.com/blog/docs/api/", "docs",
+			FileInfo{Path: "docs/api/index.md", Dir: "docs/api/", BaseName: "index", Ext: ".md"}},
+	} {
+		p := &Page{relPath: tc.relPath, outPath: tc.outPath}
+		p.setLocation(base)
+		if p.URL != tc.url || p.RelPermalink != tc.relPermalink || p.Permalink != tc.permalink || p.Section != tc.section || p.File != tc.file {
+			t.Errorf("%s: unexpected location: %q %q %q %q %+v", tc.relPath, p.URL, p.RelPermalink, p.Permalink, p.Section, p.File)
+		}
+	}
+
+	p := &Page{relPath: "about.md", outPath: "about.html"}
+	p.setLocation(&url.URL{})
+	if p.URL != "/about.html" || p.Permalink != "/about.html" {
+		t.Errorf("unexpected location without base URL: %q %q", p.URL, p.Permalink)
+	}
+}
+
+func TestParsePageFrontmatterOnly(t *testing.T) {
+	page, err := ParsePage([]byte("---\n{\"Title\": \"Index\"}\n---\n"), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != "Index" || len(page.Body) != 0 {
+		t.Fatalf("unexpected page: %q %q", page.Title, page.Body)
+	}
+}
+
+func TestRenderContentParamsHint(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":   `{}`,
+		"content/a.md": "---\n{\"Title\": \"A\", \"Author\": \"Me\"}\n---\n",
+	})
+
+	for tmpl, key := range map[string]string{
+		`{{.Page.Title}} by {{.Page.Author}}`:                "Author",
+		`{{range .Site.Pages}}{{.Params.x}}{{.Tags}}{{end}}`: "Tags",
+	} {
+		writeFiles(t, tmp, map[string]string{"templates/base.html": tmpl})
+		opts := &Options{&RawOptions{SiteDir: tmp}}
+		err := renderContent(opts)
+		expected := `frontmatter key "` + key + `" has no field on .Page, use .Page.Params.` + key
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected error containing %q, got %v", expected, err)
+		}
+	}
+}
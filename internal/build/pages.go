@@ -0,0 +1,69 @@
+package build
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+)
+
+// Pages is an ordered collection of pages, exposed to templates as
+// .Site.Pages. The sorting methods return sorted copies so they can be
+// chained in templates:
+//
+//	{{ range .Site.Pages.ByDate.Reverse }}
+type Pages []*Page
+
+// ByDate sorts oldest first.
+func (ps Pages) ByDate() Pages {
+	return ps.sorted(func(a, b *Page) int {
+		return a.Date.Compare(b.Date)
+	})
+}
+
+// ByLastMod sorts least recently modified first.
+func (ps Pages) ByLastMod() Pages {
+	return ps.sorted(func(a, b *Page) int {
+		return a.LastMod.Compare(b.LastMod)
+	})
+}
+
+// ByWeight sorts lightest first.
+func (ps Pages) ByWeight() Pages {
+	return ps.sorted(func(a, b *Page) int {
+		return cmp.Compare(a.Weight, b.Weight)
+	})
+}
+
+func (ps Pages) ByTitle() Pages {
+	return ps.sorted(func(a, b *Page) int {
+		return cmp.Compare(a.Title, b.Title)
+	})
+}
+
+func (ps Pages) Reverse() Pages {
+	out := slices.Clone(ps)
+	slices.Reverse(out)
+	return out
+}
+
+// sorted returns a stable sort of ps, falling back to the source path for
+// ties so the output never depends on walk order.
+func (ps Pages) sorted(fn func(a, b *Page) int) Pages {
+	out := slices.Clone(ps)
+	slices.SortStableFunc(out, func(a, b *Page) int {
+		if c := fn(a, b); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.relPath, b.relPath)
+	})
+	return out
+}
+
+// MarshalJSON only lists source paths to keep debug output readable.
+func (ps Pages) MarshalJSON() ([]byte, error) {
+	paths := make([]string, 0, len(ps))
+	for _, p := range ps {
+		paths = append(paths, p.relPath)
+	}
+	return json.Marshal(paths)
+}
@@ -0,0 +1,103 @@
+package build
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// RelatedConfig controls how .Page.Related is computed. It is read from the
+// Related key of yugo.jsonr.
+type RelatedConfig struct {
+	Limit int // maximum number of related pages, 5 by default
+	// Weights maps a frontmatter key to the score for each value two pages
+	// share under that key. The key "Section" scores pages in the same
+	// section instead, and is off by default so that siblings with nothing
+	// in common aren't related. A weight of 0 ignores the key.
+	Weights map[string]int
+}
+
+const sectionWeightKey = "Section"
+
+var defaultRelatedWeights = map[string]int{
+	"Tags":           1,
+	"Categories":     1,
+	"Keywords":       1,
+	sectionWeightKey: 0,
+}
+
+// termsOf returns the distinct lowercased values of a frontmatter key, which
+// may be a string or a list of strings.
+func termsOf(p *Page, key string) []string {
+	values := []any{}
+	switch v := p.Params[key].(type) {
+	case string:
+		values = append(values, v)
+	case []any:
+		values = v
+	}
+	terms := []string{}
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			continue
+		}
+		if term := strings.ToLower(strings.TrimSpace(s)); !slices.Contains(terms, term) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// relatedScore is how strongly b is related to a.
+func (rc RelatedConfig) relatedScore(a, b *Page) int {
+	score := 0
+	for key, weight := range rc.Weights {
+		if key == sectionWeightKey {
+			if a.Section == b.Section {
+				score += weight
+			}
+			continue
+		}
+		bTerms := termsOf(b, key)
+		for _, term := range termsOf(a, key) {
+			if slices.Contains(bTerms, term) {
+				score += weight
+			}
+		}
+	}
+	return score
+}
+
+// setRelated sets Related for every page to the highest scoring other pages.
+// Ties go to the newer page, then to the source path, so the result never
+// depends on walk order.
+func (s *site) setRelated(rc RelatedConfig) {
+	type scored struct {
+		page  *Page
+		score int
+	}
+	for _, p := range s.pages {
+		candidates := []scored{}
+		for _, other := range s.pages {
+			if other == p {
+				continue
+			}
+			if score := rc.relatedScore(p, other); score > 0 {
+				candidates = append(candidates, scored{other, score})
+			}
+		}
+		slices.SortFunc(candidates, func(a, b scored) int {
+			return cmp.Or(
+				cmp.Compare(b.score, a.score),
+				b.page.Date.Compare(a.page.Date),
+				cmp.Compare(a.page.relPath, b.page.relPath),
+			)
+		})
+
+		p.Related = nil
+		for _, c := range candidates[:min(len(candidates), rc.Limit)] {
+			p.Related = append(p.Related, c.page)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package build
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderContentRelated(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{range .Page.Related}}[{{.Title}}]{{end}}`,
+		"content/blog/a.md":   "---\n{\"Title\": \"A\", \"Tags\": [\"go\", \"web\"], \"Keywords\": \"templates\"}\n---\n",
+		"content/blog/b.md":   "---\n{\"Title\": \"B\", \"Tags\": [\"Go\", \"web\"]}\n---\n",
+		"content/blog/c.md":   "---\n{\"Title\": \"C\", \"Tags\": [\"rust\"], \"Date\": \"2024-01-01\"}\n---\n",
+		"content/blog/d.md":   "---\n{\"Title\": \"D\", \"Date\": \"2024-02-01\"}\n---\n",
+		"content/notes/e.md":  "---\n{\"Title\": \"E\", \"Keywords\": [\"templates\"]}\n---\n",
+		"content/notes/f.md":  "---\n{\"Title\": \"F\"}\n---\n",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir: tmp,
+		Related: RelatedConfig{
+			Limit:   3,
+			Weights: map[string]int{"Tags": 3, "Keywords": 2, "Section": 1},
+		},
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, expected := range map[string]string{
+		// B shares two tags and the section, E a keyword, then the newest
+		// page in the section.
+		"blog/a.html":  "[B][E][D]",
+		"blog/c.html":  "[D][A][B]",
+		"notes/f.html": "[E]",
+	} {
+		if out := readFile(t, filepath.Join(tmp, "public", file)); out != expected {
+			t.Errorf("%s: expected %q, got %q", file, expected, out)
+		}
+	}
+}
+
+func TestRelatedScore(t *testing.T) {
+	page := func(section string, params map[string]any) *Page {
+		return &Page{Section: section, Params: params}
+	}
+	rc := RelatedConfig{Weights: defaultRelatedWeights}
+	for _, tc := range []struct {
+		name     string
+		a, b     *Page
+		expected int
+	}{
+		{"siblings", page("blog", map[string]any{}), page("blog", map[string]any{}), 0},
+		{"shared tag", page("blog", map[string]any{"Tags": "go"}), page("notes", map[string]any{"Tags": []any{"Go"}}), 1},
+		{"duplicate terms",
+			page("blog", map[string]any{"Tags": []any{"go", "Go", " go"}}),
+			page("blog", map[string]any{"Tags": []any{"go", "go"}}), 1},
+		{"several keys",
+			page("blog", map[string]any{"Tags": []any{"go", "web"}, "Keywords": "templates"}),
+			page("blog", map[string]any{"Tags": []any{"web", "go"}, "Keywords": []any{"templates"}}), 3},
+	} {
+		if score := rc.relatedScore(tc.a, tc.b); score != tc.expected {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.expected, score)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/msolo/jsonr"
+)
+
+// FieldSchema constrains a single frontmatter key.
+type FieldSchema struct {
+	Required bool
+	Type     string // string, number, integer, bool, date, array or object
+	Enum     []any  // allowed values; applied to each element of an array
+	Format   string // Go time layout a date must be written in, see check
+}
+
+// Schema maps a section name to the rules for each frontmatter key in that
+// section. Rules in the "*" section apply to every page, but a section can
+// override them key by key.
+type Schema map[string]map[string]FieldSchema
+
+const allSections = "*"
+
+var schemaTypes = []string{"", "string", "number", "integer", "bool", "date", "array", "object"}
+
+// loadSchema reads schema.jsonr from the site directory, falling back to the
+// Schema key in yugo.jsonr. It returns nil if neither defines a schema.
+func loadSchema(opts *Options) (Schema, error) {
+	schema := opts.rawOptions.Schema
+	schemaPath := filepath.Join(opts.SiteDir(), "schema.jsonr")
+	raw, err := os.ReadFile(schemaPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	case schema != nil:
+		return nil, fmt.Errorf("schema defined in both yugo.jsonr and %s", schemaPath)
+	default:
+		if err := jsonr.Unmarshal(raw, &schema); err != nil {
+			return nil, fmt.Errorf("%s: %w", schemaPath, err)
+		}
+	}
+	if err := schema.check(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// check reports mistakes in the schema itself.
+func (s Schema) check() error {
+	for section, fields := range s {
+		for key, rule := range fields {
+			if !slices.Contains(schemaTypes, rule.Type) {
+				return fmt.Errorf("schema %s.%s: unknown type %q", section, key, rule.Type)
+			}
+			if rule.Format != "" && rule.Type != "date" {
+				return fmt.Errorf("schema %s.%s: Format requires type date", section, key)
+			}
+			// Date and LastMod are parsed before validation, so Format can
+			// only narrow the layouts they accept. RFC3339Nano parses RFC3339
+			// dates too.
+			if (key == "Date" || key == "LastMod") && rule.Format != "" &&
+				rule.Format != time.RFC3339 && !slices.Contains(dateLayouts, rule.Format) {
+				return fmt.Errorf("schema %s.%s: Format %q is not a layout accepted for %s", section, key, rule.Format, key)
+			}
+		}
+	}
+	return nil
+}
+
+// fields returns the rules for a section with the "*" rules merged in.
+func (s Schema) fields(section string) map[string]FieldSchema {
+	fields := map[string]FieldSchema{}
+	maps.Copy(fields, s[allSections])
+	maps.Copy(fields, s[section])
+	return fields
+}
+
+func (s Schema) validate(section string, fm map[string]any) error {
+	fields := s.fields(section)
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	errs := []error{}
+	for _, key := range keys {
+		rule := fields[key]
+		v, ok := fm[key]
+		if !ok {
+			if rule.Required {
+				errs = append(errs, fmt.Errorf("frontmatter key %q: required", key))
+			}
+			continue
+		}
+		if err := rule.validate(v); err != nil {
+			errs = append(errs, fmt.Errorf("frontmatter key %q: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (rule FieldSchema) validate(v any) error {
+	switch rule.Type {
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("expected string, got %s", describe(v))
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("expected number, got %s", describe(v))
+		}
+	case "integer":
+		if f, ok := v.(float64); !ok || f != math.Trunc(f) {
+			return fmt.Errorf("expected integer, got %s", describe(v))
+		}
+	case "bool":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("expected bool, got %s", describe(v))
+		}
+	case "array":
+		if _, ok := v.([]any); !ok {
+			return fmt.Errorf("expected array, got %s", describe(v))
+		}
+	case "object":
+		if _, ok := v.(map[string]any); !ok {
+			return fmt.Errorf("expected object, got %s", describe(v))
+		}
+	case "date":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("expected date, got %s", describe(v))
+		}
+		if rule.Format != "" {
+			if _, err := time.Parse(rule.Format, s); err != nil {
+				return fmt.Errorf("expected date in format %q, got %q", rule.Format, s)
+			}
+		} else if _, err := parseDate(s, time.UTC); err != nil {
+			return err
+		}
+	}
+
+	if len(rule.Enum) > 0 {
+		values := []any{v}
+		if arr, ok := v.([]any); ok {
+			values = arr
+		}
+		for _, x := range values {
+			if !inEnum(rule.Enum, x) {
+				return fmt.Errorf("%s is not one of %s", describe(x), describeAll(rule.Enum))
+			}
+		}
+	}
+	return nil
+}
+
+func inEnum(enum []any, v any) bool {
+	switch v.(type) {
+	case []any, map[string]any:
+		// Not comparable, and never a sensible enum value.
+		return false
+	}
+	return slices.Contains(enum, v)
+}
+
+// describe formats a frontmatter value for an error message.
+func describe(v any) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprint(v)
+}
+
+func describeAll(vs []any) string {
+	out := make([]string, 0, len(vs))
+	for _, v := range vs {
+		out = append(out, describe(v))
+	}
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,103 @@
+package build
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchemaValidation(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"schema.jsonr": `{
+  "*": {"Title": {"Required": true, "Type": "string"}},
+  "blog": {
+    "Date": {"Required": true, "Type": "date", "Format": "2006-01-02"},
+    "Tags": {"Type": "array", "Enum": ["go", "web"]},
+    "Status": {"Type": "string", "Enum": ["open", "closed"]},
+  },
+}`,
+		"content/index.md": "---\n{\"Title\": \"Home\"}\n---\nhi",
+		"content/about.md": "no frontmatter",
+		"content/blog/ok.md": `---
+{"Title": "OK", "Date": "2024-01-02", "Tags": ["go"]}
+---
+ok`,
+		"content/blog/bad.md": `---
+{"Title": "Bad", "Date": "Jan 2, 2024", "Tags": ["go", "rust"], "Status": 1}
+---
+bad`,
+		"content/blog/parse.md": `---
+{"Title": "Parse", "Date": "next tuesday"}
+---
+parse`,
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir: tmp,
+	}}
+	_, err := loadPages(opts)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, expected := range []string{
+		`about.md: frontmatter key "Title": required`,
+		`blog/bad.md: frontmatter key "Date": expected date in format "2006-01-02", got "Jan 2, 2024"`,
+		`blog/bad.md: frontmatter key "Status": expected string, got 1`,
+		`blog/bad.md: frontmatter key "Tags": "rust" is not one of "go", "web"`,
+		`blog/parse.md: frontmatter key "Date": unrecognized date "next tuesday"`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in:\n%s", expected, err)
+		}
+	}
+	if strings.Contains(err.Error(), "ok.md") || strings.Contains(err.Error(), "index.md") {
+		t.Errorf("valid pages reported:\n%s", err)
+	}
+}
+
+func TestSchemaInConfigAndFile(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"schema.jsonr": `{}`,
+		"content/a.md": "a",
+	})
+	opts := &Options{&RawOptions{
+		SiteDir: tmp,
+		Schema:  Schema{},
+	}}
+	if _, err := loadPages(opts); err == nil || !strings.Contains(err.Error(), "schema defined in both") {
+		t.Fatalf("expected conflict error, got: %v", err)
+	}
+}
+
+func TestSchemaDateFormat(t *testing.T) {
+	for name, tc := range map[string]struct {
+		key, format, date string
+		err               string
+	}{
+		"date":      {"Date", "02/01/2006", "02/01/2024", `schema *.Date: Format "02/01/2006" is not a layout accepted for Date`},
+		"lastmod":   {"LastMod", "02/01/2006", "02/01/2024", `schema *.LastMod: Format "02/01/2006" is not a layout accepted for LastMod`},
+		"accepted":  {"Date", "Jan 2, 2006", "Mar 4, 2024", ""},
+		"rfc3339":   {"Date", time.RFC3339, "2024-03-04T10:00:00Z", ""},
+		"other key": {"Published", "02/01/2006", "02/01/2024", ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tmp := t.TempDir()
+			writeFiles(t, tmp, map[string]string{
+				"content/a.md": "---\n{\"" + tc.key + "\": \"" + tc.date + "\"}\n---\na",
+			})
+			opts := &Options{&RawOptions{
+				SiteDir: tmp,
+				Schema:  Schema{"*": {tc.key: {Type: "date", Format: tc.format}}},
+			}}
+			_, err := loadPages(opts)
+			if tc.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Fatalf("expected %q, got: %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,416 @@
+package build
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+)
+
+// shortcodeDir holds the templates for shortcodes.
+const shortcodeDir = "_shortcodes/"
+
+// Shortcode is the data passed to a shortcode template. A shortcode is a
+// template call in Markdown content, written {{< name args >}} to insert the
+// template's output as HTML, or {{% name args %}} to insert it as Markdown.
+// Either form can enclose content up to {{< /name >}} or {{% /name %}}.
+type Shortcode struct {
+	Name string
+	// Arguments are either all named, like key="value", or all positional.
+	Params map[string]string
+	Args   []string
+	// Inner is the enclosed content with its own shortcodes expanded. For
+	// {{% %}} shortcodes it is rendered as Markdown along with the output.
+	Inner    template.HTML
+	Page     *Page
+	Site     map[string]any
+	Position string // like "post.md:12"
+}
+
+// Get returns a positional argument by index or a named one by name, or ""
+// if there is none.
+func (sc *Shortcode) Get(key any) string {
+	switch key := key.(type) {
+	case int:
+		if key >= 0 && key < len(sc.Args) {
+			return sc.Args[key]
+		}
+	case string:
+		return sc.Params[key]
+	}
+	return ""
+}
+
+// shortcodePlaceholder stands in for the output of an HTML shortcode while
+// the Markdown is rendered. It is made of private use characters, which
+// goldmark leaves alone and heading IDs leave out, with the digits of the
+// output's number shifted into that range.
+var shortcodePlaceholder = regexp.MustCompile("\uE000[\uE010-\uE019]+\uE001")
+
+// placeholder returns the placeholder for output number i.
+func placeholder(i int) string {
+	digits := strings.Map(func(r rune) rune { return r - '0' + '\uE010' }, strconv.Itoa(i))
+	return "\uE000" + digits + "\uE001"
+}
+
+// shortcodeIDs generates goldmark's heading IDs from text without shortcode
+// placeholders.
+type shortcodeIDs struct {
+	parser.IDs
+}
+
+func (ids shortcodeIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	return ids.IDs.Generate(shortcodePlaceholder.ReplaceAll(value, nil), kind)
+}
+
+// shortcodeTag is a single {{< >}} or {{% %}} tag.
+type shortcodeTag struct {
+	start, end  int // offsets of the whole tag
+	markdown    bool
+	closing     bool
+	selfClosing bool
+	escaped     string // set for {{</* */>}}, which is output as written
+	name        string
+	params      map[string]string
+	args        []string
+}
+
+// shortcodeExpander replaces the shortcodes in a page body, or in a file
+// included into it.
+type shortcodeExpander struct {
+	templates *template.Template
+	opts      *Options
+	page      *Page
+	site      map[string]any
+	body      string
+	file      string    // source of body relative to ContentDir
+	bodyLine  int       // lines before body in file
+	includes  []string  // absolute paths of the files being expanded
+	outputs   *[]string // by placeholder number, shared with includes
+}
+
+// expandShortcodes runs the shortcodes in a Markdown page body. It returns
+// the Markdown to render and a function that puts the output of HTML
+// shortcodes into the rendered HTML.
+func expandShortcodes(page *Page, site *site, opts *Options) (string, func(string) string, error) {
+	e := &shortcodeExpander{
+		templates: site.templates,
+		opts:      opts,
+		page:      page,
+		site:      site.config,
+		body:      string(page.Body),
+		file:      page.relPath,
+		bodyLine:  page.bodyLine,
+		includes:  []string{absPath(page.path)},
+		outputs:   &[]string{},
+	}
+	md, err := e.expand(0, len(e.body))
+	if err != nil {
+		return "", nil, err
+	}
+	return md, e.restore, nil
+}
+
+// restore replaces placeholders with shortcode output. A placeholder alone
+// in a paragraph replaces the paragraph. Later placeholders can only appear
+// in the output of earlier ones, so one pass in reverse covers nesting.
+func (e *shortcodeExpander) restore(htmlStr string) string {
+	outputs := *e.outputs
+	for i := len(outputs) - 1; i >= 0; i-- {
+		ph := placeholder(i)
+		htmlStr = strings.Replace(htmlStr, "<p>"+ph+"</p>", outputs[i], 1)
+		htmlStr = strings.Replace(htmlStr, ph, outputs[i], 1)
+	}
+	return htmlStr
+}
+
+// line returns the source line of an offset into the body.
+func (e *shortcodeExpander) line(offset int) int {
+	return e.bodyLine + 1 + strings.Count(e.body[:offset], "\n")
+}
+
+func (e *shortcodeExpander) errorf(offset int, format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", e.line(offset), fmt.Sprintf(format, args...))
+}
+
+// expand returns body[start:end] with every shortcode replaced.
+func (e *shortcodeExpander) expand(start, end int) (string, error) {
+	out := strings.Builder{}
+	pos := start
+	for {
+		tag, err := e.nextTag(pos, end)
+		if err != nil {
+			return "", err
+		}
+		if tag == nil {
+			out.WriteString(e.body[pos:end])
+			return out.String(), nil
+		}
+		out.WriteString(e.body[pos:tag.start])
+		pos = tag.end
+
+		switch {
+		case tag.escaped != "":
+			out.WriteString(tag.escaped)
+			continue
+		case tag.closing:
+			return "", e.errorf(tag.start, "closing shortcode %q without an opening one", tag.name)
+		}
+
+		inner := ""
+		if !tag.selfClosing {
+			closeTag, err := e.findClose(tag, end)
+			if err != nil {
+				return "", err
+			}
+			if closeTag != nil {
+				inner, err = e.expand(tag.end, closeTag.start)
+				if err != nil {
+					return "", err
+				}
+				pos = closeTag.end
+			}
+		}
+
+		switch tag.name {
+		case includeShortcode, snippetShortcode:
+			builtin := e.include
+			if tag.name == snippetShortcode {
+				builtin = e.snippet
+			}
+			output, err := builtin(tag, inner)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(output)
+			continue
+		}
+
+		output, err := e.call(tag, inner)
+		if err != nil {
+			return "", err
+		}
+		if tag.markdown {
+			out.WriteString(output)
+		} else {
+			out.WriteString(placeholder(len(*e.outputs)))
+			*e.outputs = append(*e.outputs, output)
+		}
+	}
+}
+
+// findClose returns the tag closing open, or nil if it has none.
+func (e *shortcodeExpander) findClose(open *shortcodeTag, end int) (*shortcodeTag, error) {
+	depth := 0
+	for pos := open.end; ; {
+		tag, err := e.nextTag(pos, end)
+		if err != nil || tag == nil {
+			return nil, err
+		}
+		pos = tag.end
+		if tag.name != open.name || tag.escaped != "" || tag.selfClosing {
+			continue
+		}
+		if !tag.closing {
+			depth++
+			continue
+		}
+		if depth == 0 {
+			if tag.markdown != open.markdown {
+				return nil, e.errorf(tag.start, "shortcode %q closed with different delimiters", tag.name)
+			}
+			return tag, nil
+		}
+		depth--
+	}
+}
+
+// call executes a shortcode's template.
+func (e *shortcodeExpander) call(tag *shortcodeTag, inner string) (string, error) {
+	var tmpl *template.Template
+	if e.templates != nil {
+		tmpl = e.templates.Lookup(shortcodeDir + tag.name + ".html")
+	}
+	if tmpl == nil {
+		return "", e.errorf(tag.start, "unknown shortcode %q: no template %s%s.html", tag.name, shortcodeDir, tag.name)
+	}
+
+	sc := &Shortcode{
+		Name:     tag.name,
+		Params:   tag.params,
+		Args:     tag.args,
+		Inner:    template.HTML(inner),
+		Page:     e.page,
+		Site:     e.site,
+		Position: fmt.Sprintf("%s:%d", filepath.ToSlash(e.file), e.line(tag.start)),
+	}
+	b := &bytes.Buffer{}
+	if err := tmpl.Execute(b, sc); err != nil {
+		return "", e.errorf(tag.start, "shortcode %q: %s", tag.name, err)
+	}
+	return b.String(), nil
+}
+
+// nextTag finds the first tag in body[pos:end].
+func (e *shortcodeExpander) nextTag(pos, end int) (*shortcodeTag, error) {
+	for {
+		i := strings.Index(e.body[pos:end], "{{")
+		if i < 0 {
+			return nil, nil
+		}
+		start := pos + i
+		if start+2 < end && (e.body[start+2] == '<' || e.body[start+2] == '%') {
+			return e.parseTag(start, end)
+		}
+		pos = start + 2
+	}
+}
+
+// parseTag parses the tag starting at body[start].
+func (e *shortcodeExpander) parseTag(start, end int) (*shortcodeTag, error) {
+	tag := &shortcodeTag{start: start, markdown: e.body[start+2] == '%'}
+	closeDelim := ">}}"
+	if tag.markdown {
+		closeDelim = "%}}"
+	}
+
+	rest := e.body[start+3 : end]
+	trimmed := strings.TrimLeftFunc(rest, unicode.IsSpace)
+	if strings.HasPrefix(trimmed, "/*") {
+		// {{</* name */>}} is output as {{< name >}}.
+		i := strings.Index(trimmed, "*/"+closeDelim)
+		if i < 0 {
+			return nil, e.errorf(start, "unclosed shortcode comment")
+		}
+		tag.end = end - len(trimmed) + i + len("*/"+closeDelim)
+		tag.escaped = e.body[start:start+3] + strings.TrimPrefix(trimmed[:i], "/*") + closeDelim
+		return tag, nil
+	}
+
+	i := strings.Index(rest, closeDelim)
+	if i < 0 {
+		return nil, e.errorf(start, "unclosed shortcode")
+	}
+	tag.end = start + 3 + i + len(closeDelim)
+	inside := strings.TrimSpace(rest[:i])
+	if strings.HasSuffix(inside, "/") {
+		tag.selfClosing = true
+		inside = strings.TrimSpace(strings.TrimSuffix(inside, "/"))
+	}
+	if strings.HasPrefix(inside, "/") {
+		tag.closing = true
+		inside = strings.TrimSpace(strings.TrimPrefix(inside, "/"))
+	}
+
+	words, err := splitShortcodeArgs(inside)
+	if err != nil {
+		return nil, e.errorf(start, "%s", err)
+	}
+	if len(words) == 0 || strings.Contains(words[0], "=") {
+		return nil, e.errorf(start, "shortcode without a name")
+	}
+	tag.name = words[0]
+	if tag.closing && len(words) > 1 {
+		return nil, e.errorf(start, "closing shortcode %q with arguments", tag.name)
+	}
+
+	for _, w := range words[1:] {
+		key, value, named := strings.Cut(w, "=")
+		if named && isShortcodeKey(key) {
+			if len(tag.args) > 0 {
+				return nil, e.errorf(start, "shortcode %q mixes named and positional arguments", tag.name)
+			}
+			if tag.params == nil {
+				tag.params = map[string]string{}
+			}
+			v, err := unquoteShortcodeArg(value)
+			if err != nil {
+				return nil, e.errorf(start, "shortcode %q: %s", tag.name, err)
+			}
+			tag.params[key] = v
+			continue
+		}
+		if tag.params != nil {
+			return nil, e.errorf(start, "shortcode %q mixes named and positional arguments", tag.name)
+		}
+		v, err := unquoteShortcodeArg(w)
+		if err != nil {
+			return nil, e.errorf(start, "shortcode %q: %s", tag.name, err)
+		}
+		tag.args = append(tag.args, v)
+	}
+	if tag.params == nil {
+		tag.params = map[string]string{}
+	}
+	return tag, nil
+}
+
+func isShortcodeKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// splitShortcodeArgs splits on spaces outside of quoted strings, keeping
+// the quotes so that unquoteShortcodeArg can tell quoted values apart.
+func splitShortcodeArgs(s string) ([]string, error) {
+	words := []string{}
+	word := strings.Builder{}
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '`':
+			j := i + 1
+			for ; j < len(s) && s[j] != c; j++ {
+				if c == '"' && s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, errors.New("unterminated quoted argument")
+			}
+			word.WriteString(s[i : j+1])
+			inWord = true
+			i = j
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func unquoteShortcodeArg(s string) (string, error) {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '`') {
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return "", fmt.Errorf("bad quoted argument %s", s)
+		}
+		return v, nil
+	}
+	return s, nil
+}
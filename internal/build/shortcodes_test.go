@@ -0,0 +1,97 @@
+package build
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderContentShortcodes(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":                        `{"Title": "Site"}`,
+		"templates/base.html":               `{{.Content}}`,
+		"templates/_shortcodes/figure.html": `<figure><img src="{{.Get "src"}}"><figcaption>{{.Get "caption"}}</figcaption></figure>`,
+		"templates/_shortcodes/kbd.html":    `<kbd>{{.Get 0}}</kbd>{{range $i, $k := .Args}}{{if $i}}+{{$k}}{{end}}{{end}}`,
+		"templates/_shortcodes/note.html":   "<div class=\"note\">\n\n{{.Inner}}\n\n</div>",
+		"templates/_shortcodes/title.html":  `{{.Page.Title}} on {{.Site.Title}} at {{.Position}}`,
+		"content/page.md": "---\n{\"Title\": \"Page\"}\n---\n" +
+			"{{< figure src=\"a.jpg\" caption=`Say \"hi\"` >}}\n\n" +
+			"Press {{< kbd Ctrl C \"V W\" />}} in {{% title %}}.\n\n" +
+			"{{% note %}}\n*Careful* with {{< kbd Esc >}}.\n{{% /note %}}\n\n" +
+			"Write `{{</* kbd X */>}}` to get a key.\n",
+	})
+
+	opts := &Options{&RawOptions{SiteDir: tmp}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<figure><img src="a.jpg"><figcaption>Say &#34;hi&#34;</figcaption></figure>
+<p>Press <kbd>Ctrl</kbd>+C+V W in Page on Site at page.md:6.</p>
+<div class="note">
+<p><em>Careful</em> with <kbd>Esc</kbd>.</p>
+</div>
+<p>Write <code>{{&lt; kbd X &gt;}}</code> to get a key.</p>
+`
+	if out := readFile(t, filepath.Join(tmp, "public/page.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+}
+
+func TestRenderContentShortcodeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		body string
+		err  string
+	}{
+		{"unknown", "Text\n\n{{< missing >}}\n", `page.md: line 6: unknown shortcode "missing"`},
+		{"unclosed", "{{< kbd\n", "page.md: line 4: unclosed shortcode"},
+		{"stray close", "x\n{{% /kbd %}}\n", `page.md: line 5: closing shortcode "kbd" without an opening one`},
+		{"mixed args", "{{< kbd a b=c >}}\n", `page.md: line 4: shortcode "kbd" mixes named and positional arguments`},
+		{"template", "\n{{< kbd >}}\n", `page.md: line 5: shortcode "kbd": template: _shortcodes/kbd.html:1:`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			writeFiles(t, tmp, map[string]string{
+				"site.jsonr":                     `{}`,
+				"templates/base.html":            `{{.Content}}`,
+				"templates/_shortcodes/kbd.html": `{{index .Args 3}}`,
+				"content/page.md":                "---\n{}\n---\n" + tc.body,
+			})
+			err := renderContent(&Options{&RawOptions{SiteDir: tmp}})
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestRenderContentShortcodeInHeading(t *testing.T) {
+	for _, style := range []string{"goldmark", "github"} {
+		t.Run(style, func(t *testing.T) {
+			tmp := t.TempDir()
+			writeFiles(t, tmp, map[string]string{
+				"site.jsonr":                         `{}`,
+				"templates/base.html":                `{{.Page.Title}}|{{range .Page.TOCItems}}{{.Text}}:{{.ID}};{{end}}|{{.Content}}|{{.Page.WordCount}}`,
+				"templates/_shortcodes/version.html": `<b>1.2</b>`,
+				"content/page.md":                    "# Guide {{< version >}}\n\n## Version {{< version >}}\n\n## {{< version >}} v\n",
+			})
+
+			opts := &Options{&RawOptions{
+				SiteDir:           tmp,
+				HeadingIDs:        style,
+				DeriveTitle:       true,
+				StripTitleHeading: true,
+			}}
+			if err := renderContent(opts); err != nil {
+				t.Fatal(err)
+			}
+
+			expected := "Guide|Version:version;v:v;|<h2 id=\"version\">Version <b>1.2</b></h2>\n<h2 id=\"v\"><b>1.2</b> v</h2>\n|4"
+			if out := readFile(t, filepath.Join(tmp, "public/page.html")); out != expected {
+				t.Fatalf("output diff:\n%s", strDiff(expected, out))
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// site is everything loaded before any single page is rendered.
+type site struct {
+	config   map[string]any   // site.jsonr plus computed values, exposed as .Site
+	pages    Pages            // published pages in content order
+	byPath   map[string]*Page // keyed by source path relative to ContentDir
+	dirPages map[string]*Page // see dirPage
+	menus    map[string][]*MenuEntry
+	wiki     *WikiIndex // built before any page is converted, see deriveTitle
+
+	templates *template.Template // for Markdown render hooks, may be nil
+	highlight bool               // some page has highlighted code blocks
+}
+
+func newSite(config map[string]any, pages Pages) *site {
+	s := &site{
+		config:   maps.Clone(config),
+		pages:    pages,
+		byPath:   map[string]*Page{},
+		dirPages: map[string]*Page{},
+	}
+	if s.config == nil {
+		s.config = map[string]any{}
+	}
+	s.config["Pages"] = pages
+	for _, p := range pages {
+		s.byPath[p.relPath] = p
+	}
+	s.wiki = NewWikiIndex(pages)
+	return s
+}
+
+// dependencies returns the files outside dir that pages included, in sorted
+// order.
+func (s *site) dependencies(dir string) []string {
+	deps := []string{}
+	for _, p := range s.pages {
+		for _, path := range p.includes {
+			if rel, err := absRel(dir, path); err == nil && filepath.IsLocal(rel) {
+				continue
+			}
+			deps = append(deps, path)
+		}
+	}
+	slices.Sort(deps)
+	return slices.Compact(deps)
+}
+
+// loadPages reads and parses every content file that will be rendered.
+// Drafts are skipped unless the options say otherwise. Frontmatter problems
+// are collected so they can all be reported at once, as BuildErrors along
+// with the pages that did load.
+func loadPages(opts *Options) (Pages, error) {
+	pages := Pages{}
+	if _, err := os.Stat(opts.ContentDir()); errors.Is(err, fs.ErrNotExist) {
+		return pages, nil
+	}
+
+	schema, err := loadSchema(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	buildErrs := BuildErrors{}
+	byOutPath := map[string]string{} // output path to the source that claimed it
+	err = filepath.WalkDir(opts.ContentDir(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk dir failed: %w", err)
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		if d.Type()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("symlinks not handled: %s", path)
+		}
+
+		if !shouldProcessFile(path) {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(opts.ContentDir(), path)
+		page, err := loadPage(path, rel, opts)
+		if err != nil {
+			buildErrs = append(buildErrs, PageError{Source: rel, Err: err})
+			return nil
+		}
+		if page.Draft && !opts.BuildDrafts() {
+			return nil
+		}
+		if err := schema.validate(sectionOf(rel), page.frontmatter); err != nil {
+			buildErrs = append(buildErrs, PageError{Source: rel, Err: err})
+			return nil
+		}
+		if other, ok := byOutPath[page.outPath]; ok {
+			err := fmt.Errorf("output path %s is already used by %s", page.outPath, other)
+			buildErrs = append(buildErrs, PageError{Source: rel, Err: err})
+			return nil
+		}
+		byOutPath[page.outPath] = rel
+		pages = append(pages, page)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(buildErrs) > 0 {
+		return pages, buildErrs
+	}
+	return pages, nil
+}
+
+// Sources maps the output path of every page, relative to OutDir with
+// forward slashes, to its source file relative to SiteDir. Pages that fail
+// to load are left out.
+func Sources(opts *Options) (map[string]string, error) {
+	pages, err := loadPages(opts)
+	if err != nil && !errors.As(err, &BuildErrors{}) {
+		return nil, err
+	}
+	sources := map[string]string{}
+	for _, p := range pages {
+		src, err := filepath.Rel(opts.SiteDir(), p.path)
+		if err != nil {
+			return nil, err
+		}
+		sources[filepath.ToSlash(p.outPath)] = filepath.ToSlash(src)
+	}
+	return sources, nil
+}
+
+// loadPage reads a content file and parses its frontmatter.
+func loadPage(path string, relPath string, opts *Options) (*Page, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := ParsePage(src, opts.Location())
+	if err != nil {
+		return nil, err
+	}
+	page.path = path
+	page.relPath = relPath
+
+	outPath := relPath
+	if ext := filepath.Ext(relPath); strings.ToLower(ext) == ".md" {
+		outPath = outPath[:len(outPath)-len(ext)] + ".html"
+	}
+	if _, ok := page.frontmatter["Slug"]; ok {
+		if err := checkSlug(page.Slug); err != nil {
+			return nil, fmt.Errorf("frontmatter key \"Slug\": %w", err)
+		}
+		outPath = filepath.Join(filepath.Dir(outPath), page.Slug+".html")
+	}
+	page.outPath = outPath
+	page.setLocation(opts.BaseURL())
+	if page.isIndex() {
+		if err := page.loadResources(opts.BaseURL()); err != nil {
+			return nil, err
+		}
+	}
+
+	return &page, nil
+}
+
+// checkSlug makes sure a slug names a visible file in the page's own
+// directory.
+func checkSlug(slug string) error {
+	switch {
+	case strings.TrimSpace(slug) == "":
+		return errors.New("must not be empty")
+	case strings.ContainsAny(slug, `/\`) || strings.Contains(slug, ".."):
+		return fmt.Errorf("%q must not contain a path separator or \"..\"", slug)
+	case strings.HasPrefix(slug, "."):
+		return fmt.Errorf("%q must not start with \".\"", slug)
+	}
+	return nil
+}
+
+// sectionOf returns the top-level content directory of a page, or "" for
+// pages at the root of the content directory.
+func sectionOf(relPath string) string {
+	dir, _, found := strings.Cut(filepath.ToSlash(relPath), "/")
+	if !found {
+		return ""
+	}
+	return dir
+}
@@ -0,0 +1,196 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+// snippetShortcode is the built-in shortcode that puts source code from the
+// repository in a fenced code block:
+//
+//	{{% snippet "cmd/main.go" %}}
+//	{{% snippet file="cmd/main.go" region="flags" %}}
+//	{{% snippet file="cmd/main.go" lines="10-20" lang="go" %}}
+//
+// The file is relative to RepoDir. A region is the lines between
+// "snippet:start name" and "snippet:end" comments. Lines with markers are
+// always left out.
+const snippetShortcode = "snippet"
+
+var (
+	snippetStart = regexp.MustCompile(`snippet:start\s+([\w.-]+)`)
+	snippetEnd   = regexp.MustCompile(`snippet:end\b\s*([\w.-]*)`)
+)
+
+// snippet returns the fenced code block for a snippet shortcode.
+func (e *shortcodeExpander) snippet(tag *shortcodeTag, inner string) (string, error) {
+	indent, err := e.blockIndent(tag)
+	if err != nil {
+		return "", err
+	}
+	if inner != "" {
+		return "", e.errorf(tag.start, "shortcode %q takes no content", tag.name)
+	}
+	for key := range tag.params {
+		switch key {
+		case "file", "region", "lines", "lang":
+		default:
+			return "", e.errorf(tag.start, "shortcode %q: unknown parameter %q", tag.name, key)
+		}
+	}
+	file := tag.params["file"]
+	if len(tag.args) == 1 {
+		file = tag.args[0]
+	}
+	if file == "" || len(tag.args) > 1 {
+		return "", e.errorf(tag.start, "shortcode %q takes a file", tag.name)
+	}
+	region, lineRange := tag.params["region"], tag.params["lines"]
+	if region != "" && lineRange != "" {
+		return "", e.errorf(tag.start, "shortcode %q takes a region or lines, not both", tag.name)
+	}
+
+	path := filepath.Join(e.opts.RepoDir(), strings.TrimPrefix(file, "/"))
+	if rel, err := absRel(e.opts.RepoDir(), path); err != nil || !filepath.IsLocal(rel) {
+		return "", e.errorf(tag.start, "snippet outside the repository: %s", file)
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", e.errorf(tag.start, "snippet: %s", err)
+	}
+	e.page.includes = append(e.page.includes, absPath(path))
+
+	lines := strings.SplitAfter(string(src), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	switch {
+	case region != "":
+		lines, err = snippetRegion(lines, region)
+	case lineRange != "":
+		lines, err = snippetLines(lines, lineRange)
+	}
+	lines = dropSnippetMarkers(lines)
+	if err != nil {
+		return "", e.errorf(tag.start, "snippet %s: %s", file, err)
+	}
+	code := dedent(strings.Join(lines, ""))
+
+	lang := tag.params["lang"]
+	if lang == "" {
+		lang = snippetLang(path)
+	}
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return indentLines(fence+lang+"\n"+code+fence, indent), nil
+}
+
+// snippetRegion returns the lines of a named region.
+func snippetRegion(lines []string, name string) ([]string, error) {
+	start := -1
+	open := []string{}
+	for i, line := range lines {
+		if m := snippetStart.FindStringSubmatch(line); m != nil {
+			if m[1] == name && start < 0 {
+				start = i + 1
+			}
+			open = append(open, m[1])
+			continue
+		}
+		m := snippetEnd.FindStringSubmatch(line)
+		if m == nil || len(open) == 0 {
+			continue
+		}
+		closed := open[len(open)-1]
+		if m[1] != "" {
+			closed = m[1]
+		}
+		for j := len(open) - 1; j >= 0; j-- {
+			if open[j] == closed {
+				open = append(open[:j], open[j+1:]...)
+				break
+			}
+		}
+		if closed == name && start >= 0 {
+			return lines[start:i], nil
+		}
+	}
+	if start >= 0 {
+		return nil, fmt.Errorf("region %q has no snippet:end", name)
+	}
+	return nil, fmt.Errorf("no region %q", name)
+}
+
+// snippetLines returns the lines in a range like "10-20", "10-" or "10",
+// counting from 1.
+func snippetLines(lines []string, lineRange string) ([]string, error) {
+	first, last, isRange := strings.Cut(lineRange, "-")
+	start, err := strconv.Atoi(strings.TrimSpace(first))
+	end := start
+	if err == nil && isRange {
+		end = len(lines)
+		if last = strings.TrimSpace(last); last != "" {
+			end, err = strconv.Atoi(last)
+		}
+	}
+	if err != nil || start < 1 || end < start || end > len(lines) {
+		return nil, fmt.Errorf("invalid lines %q for a file of %d lines", lineRange, len(lines))
+	}
+	return lines[start-1 : end], nil
+}
+
+func dropSnippetMarkers(lines []string) []string {
+	out := []string{}
+	for _, line := range lines {
+		if !snippetStart.MatchString(line) && !snippetEnd.MatchString(line) {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// dedent removes the indentation common to every non-blank line, and makes
+// sure the code ends with a newline.
+func dedent(code string) string {
+	lines := strings.SplitAfter(strings.TrimRight(code, "\n")+"\n", "\n")
+	prefix := ""
+	first := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		ws := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = ws, false
+		}
+		for !strings.HasPrefix(ws, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	out := strings.Builder{}
+	for _, line := range lines {
+		out.WriteString(strings.TrimPrefix(line, prefix))
+	}
+	return strings.TrimLeft(out.String(), "\n")
+}
+
+// snippetLang returns the fenced code language for a file, like "go", or ""
+// if chroma doesn't know it.
+func snippetLang(path string) string {
+	lexer := lexers.Match(filepath.Base(path))
+	if lexer == nil {
+		return ""
+	}
+	if aliases := lexer.Config().Aliases; len(aliases) > 0 {
+		return aliases[0]
+	}
+	return strings.ToLower(lexer.Config().Name)
+}
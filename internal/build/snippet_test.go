@@ -0,0 +1,112 @@
+package build
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const snippetSource = `package main
+
+// snippet:start imports
+import "fmt"
+// snippet:end
+
+func main() {
+	// snippet:start body
+	fmt.Println("hello")
+	// snippet:start inner
+	fmt.Println("` + "```" + `")
+	// snippet:end inner
+	// snippet:end body
+}
+`
+
+func TestRenderContentSnippet(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		".git/HEAD":                "",
+		"src/main.go":              snippetSource,
+		"src/notes.unknown":        "plain\n",
+		"site/site.jsonr":          `{}`,
+		"site/templates/base.html": `{{.Content}}`,
+		"site/content/page.md": "{{% snippet file=\"src/main.go\" region=\"imports\" %}}\n\n" +
+			"- Body:\n\n  {{% snippet file=\"/src/main.go\" region=\"body\" %}}\n\n" +
+			"{{% snippet file=\"src/main.go\" lines=\"7-9\" lang=\"text\" %}}\n\n" +
+			"{{% snippet \"src/notes.unknown\" %}}\n",
+	})
+
+	opts := &Options{&RawOptions{SiteDir: filepath.Join(tmp, "site")}}
+	site, err := renderSite(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<pre><code class="language-go">import &quot;fmt&quot;
+</code></pre>
+<ul>
+<li>
+<p>Body:</p>
+<pre><code class="language-go">fmt.Println(&quot;hello&quot;)
+fmt.Println(&quot;` + "```" + `&quot;)
+</code></pre>
+</li>
+</ul>
+<pre><code class="language-text">func main() {
+	fmt.Println(&quot;hello&quot;)
+</code></pre>
+<pre><code>plain
+</code></pre>
+`
+	if out := readFile(t, filepath.Join(tmp, "site/public/page.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+	if deps := site.dependencies(opts.SiteDir()); len(deps) != 2 {
+		t.Fatalf("expected both snippet files as dependencies, got %q", deps)
+	}
+}
+
+func TestRenderContentSnippetErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		page string
+		err  string
+	}{
+		"missing region": {
+			page: "{{% snippet file=\"main.go\" region=\"gone\" %}}\n",
+			err:  `page.md: line 1: snippet main.go: no region "gone"`,
+		},
+		"unclosed region": {
+			page: "{{% snippet file=\"open.go\" region=\"a\" %}}\n",
+			err:  `page.md: line 1: snippet open.go: region "a" has no snippet:end`,
+		},
+		"bad lines": {
+			page: "{{% snippet file=\"main.go\" lines=\"10-100\" %}}\n",
+			err:  `page.md: line 1: snippet main.go: invalid lines "10-100" for a file of 14 lines`,
+		},
+		"unknown parameter": {
+			page: "{{% snippet file=\"main.go\" region=\"body\" from=\"3\" %}}\n",
+			err:  `page.md: line 1: shortcode "snippet": unknown parameter "from"`,
+		},
+		"outside": {
+			page: "{{% snippet \"../main.go\" %}}\n",
+			err:  "page.md: line 1: snippet outside the repository: ../main.go",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tmp := t.TempDir()
+			writeFiles(t, tmp, map[string]string{
+				"main.go":             snippetSource,
+				"open.go":             "// snippet:start a\nx\n",
+				"site.jsonr":          `{}`,
+				"templates/base.html": `{{.Content}}`,
+				"content/page.md":     tc.page,
+			})
+
+			opts := &Options{&RawOptions{SiteDir: tmp}}
+			err := renderContent(opts)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// html/template substitutes this marker for values it refuses to emit in
+// a given context, such as an unsafe URL. Only attribute values are
+// checked, so that text mentioning the marker is left alone.
+const zgotmplZ = "ZgotmplZ"
+
+// PageError records a failure to build a single content file.
+type PageError struct {
+	Source string // path relative to ContentDir
+	Err    error
+}
+
+func (e PageError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Source, e.Err)
+}
+
+func (e PageError) Unwrap() error {
+	return e.Err
+}
+
+// BuildErrors collects page failures so they can be reported in one
+// summary rather than stopping at the first one.
+type BuildErrors []PageError
+
+func (be BuildErrors) Error() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%d page(s) failed:", len(be))
+	for _, pe := range be {
+		for line := range strings.Lines(pe.Err.Error()) {
+			fmt.Fprintf(b, "\n  %s: %s", pe.Source, strings.TrimRight(line, "\n"))
+		}
+	}
+	return b.String()
+}
+
+// strictErrors turns the warnings and the sanitizer markers found in a
+// rendered page into errors.
+func strictErrors(warnings []string, out string) error {
+	errs := []error{}
+	for _, w := range warnings {
+		errs = append(errs, errors.New(w))
+	}
+	z := html.NewTokenizer(strings.NewReader(out))
+	line := 1
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		tokLine := line
+		line += strings.Count(string(z.Raw()), "\n")
+
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		for _, a := range z.Token().Attr {
+			if strings.Contains(a.Val, zgotmplZ) {
+				errs = append(errs, fmt.Errorf("output line %d: unsafe value replaced with %s in %s", tokLine, zgotmplZ, a.Key))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,123 @@
+package build
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// summaryDivider marks the end of a hand-written summary in the body.
+const summaryDivider = "<!--more-->"
+
+// wordsPerMinute is used to estimate ReadingTime.
+const wordsPerMinute = 200
+
+// setContent sets the rendered body of a page along with its summary and
+// text statistics.
+//
+// The summary is summaryHTML, the part of htmlStr before a <!--more-->
+// divider, if there is one, then a Summary frontmatter key, and finally the
+// first summaryLength words of the text.
+func (p *Page) setContent(htmlStr, summaryHTML string, hasDivider bool, summaryLength int) {
+	p.Content = template.HTML(htmlStr)
+	p.PlainText = plainText(htmlStr)
+	words := strings.Fields(p.PlainText)
+	p.WordCount = len(words)
+	p.ReadingTime = (p.WordCount + wordsPerMinute - 1) / wordsPerMinute
+
+	switch {
+	case hasDivider:
+		p.Summary = template.HTML(summaryHTML)
+		p.Truncated = strings.TrimSpace(strings.TrimPrefix(htmlStr, summaryHTML)) != ""
+	case p.Summary != "":
+		p.Truncated = true
+	case len(words) > summaryLength:
+		p.Summary = template.HTML(template.HTMLEscapeString(strings.Join(words[:summaryLength], " ")))
+		p.Truncated = true
+	default:
+		p.Summary = template.HTML(template.HTMLEscapeString(strings.Join(words, " ")))
+	}
+}
+
+// splitSummary moves the top-level blocks before a <!--more--> line into a
+// document of their own and drops the divider, so the summary is rendered
+// from whole blocks whether or not raw HTML is output. It returns nil if
+// there is no divider. A divider within a paragraph is ignored with a
+// warning.
+func splitSummary(doc *ast.Document, source []byte, warn func(string)) *ast.Document {
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		raw, ok := n.(*ast.RawHTML)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		value := []byte{}
+		for i := 0; i < raw.Segments.Len(); i++ {
+			seg := raw.Segments.At(i)
+			value = append(value, seg.Value(source)...)
+		}
+		if string(value) == summaryDivider {
+			warn(summaryDivider + " within a paragraph is ignored, put it on a line of its own")
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		b, ok := n.(*ast.HTMLBlock)
+		if !ok || strings.TrimSpace(string(b.Lines().Value(source))) != summaryDivider {
+			continue
+		}
+		summary := ast.NewDocument()
+		for c := doc.FirstChild(); c != n; c = doc.FirstChild() {
+			summary.AppendChild(summary, c)
+		}
+		doc.RemoveChild(doc, n)
+		return summary
+	}
+	return nil
+}
+
+// plainText strips the markup from an HTML fragment, leaving words separated
+// by single spaces. Scripts, styles and comments are dropped.
+func plainText(htmlStr string) string {
+	z := html.NewTokenizer(strings.NewReader(htmlStr))
+	b := &strings.Builder{}
+	skip := 0
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(b.String()), " ")
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(html.UnescapeString(string(z.Text())))
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			a := atom.Lookup(name)
+			switch a {
+			case atom.Script, atom.Style:
+				if tt == html.StartTagToken {
+					skip++
+				} else if tt == html.EndTagToken && skip > 0 {
+					skip--
+				}
+			}
+			if !inlineAtoms[a] {
+				// Keep words in adjacent blocks from running together.
+				b.WriteString(" ")
+			}
+		}
+	}
+}
+
+var inlineAtoms = map[atom.Atom]bool{
+	atom.A: true, atom.Abbr: true, atom.B: true, atom.Bdi: true, atom.Bdo: true,
+	atom.Cite: true, atom.Code: true, atom.Data: true, atom.Del: true, atom.Dfn: true,
+	atom.Em: true, atom.I: true, atom.Ins: true, atom.Kbd: true, atom.Mark: true,
+	atom.Q: true, atom.S: true, atom.Samp: true, atom.Small: true, atom.Span: true,
+	atom.Strong: true, atom.Sub: true, atom.Sup: true, atom.Time: true, atom.U: true,
+	atom.Var: true,
+}
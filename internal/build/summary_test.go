@@ -0,0 +1,150 @@
+package build
+
+import (
+	"bytes"
+	"html/template"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+func TestPlainText(t *testing.T) {
+	in := `<h1 id="x">Title</h1><p>Some <em>emphasized</em> text&amp;more.</p><script>var x = 1;</script><ul><li>one</li><li>two</li></ul>`
+	expected := "Title Some emphasized text&more. one two"
+	if out := plainText(in); out != expected {
+		t.Fatalf("expected %q got %q", expected, out)
+	}
+}
+
+func TestSummaryDivider(t *testing.T) {
+	p := &Page{}
+	p.setContent("<p>Intro.</p>\n<p>Rest of it.</p>", "<p>Intro.</p>\n", true, 70)
+	if p.Summary != "<p>Intro.</p>\n" || !p.Truncated {
+		t.Fatalf("unexpected summary: %q truncated: %v", p.Summary, p.Truncated)
+	}
+	if p.WordCount != 4 || p.ReadingTime != 1 {
+		t.Fatalf("unexpected word count %d reading time %d", p.WordCount, p.ReadingTime)
+	}
+}
+
+func TestSummaryFrontmatter(t *testing.T) {
+	p := &Page{Summary: "Hand <written>"}
+	p.setContent("<p>Body text.</p>", "", false, 70)
+	if p.Summary != "Hand <written>" || !p.Truncated {
+		t.Fatalf("unexpected summary: %q", p.Summary)
+	}
+}
+
+func TestSummaryAuto(t *testing.T) {
+	p := &Page{}
+	p.setContent("<p>one two <b>three</b> four &lt;five&gt;</p>", "", false, 4)
+	if p.Summary != template.HTML("one two three four") || !p.Truncated {
+		t.Fatalf("unexpected summary: %q truncated: %v", p.Summary, p.Truncated)
+	}
+
+	p = &Page{}
+	p.setContent("<p>short &amp; sweet</p>", "", false, 4)
+	if p.Summary != template.HTML("short &amp; sweet") || p.Truncated {
+		t.Fatalf("unexpected summary: %q truncated: %v", p.Summary, p.Truncated)
+	}
+}
+
+func TestRenderContentSummaryDivider(t *testing.T) {
+	for name, tc := range map[string]struct {
+		file    string
+		unsafe  bool
+		body    string
+		summary string
+		content string
+	}{
+		"line": {
+			file:    "page.md",
+			unsafe:  true,
+			body:    "Intro *text*.\n<!--more-->\nRest.\n",
+			summary: "<p>Intro <em>text</em>.</p>\n",
+			content: "<p>Intro <em>text</em>.</p>\n<p>Rest.</p>\n",
+		},
+		"safe": {
+			file:    "page.md",
+			body:    "Intro.\n\n<!--more-->\n\nRest.\n",
+			summary: "<p>Intro.</p>\n",
+			content: "<p>Intro.</p>\n<p>Rest.</p>\n",
+		},
+		"inline": {
+			file:    "page.md",
+			unsafe:  true,
+			body:    "Intro text <!--more--> rest.\n",
+			summary: "Intro text rest.", // a divider within a paragraph is ignored
+			content: "<p>Intro text <!--more--> rest.</p>\n",
+		},
+		"html": {
+			file:    "page.html",
+			body:    "<p>Intro.</p>\n<!--more-->\n<p>Rest.</p>\n",
+			summary: "<p>Intro.</p>\n",
+			content: "<p>Intro.</p>\n\n<p>Rest.</p>\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tmp := t.TempDir()
+			writeFiles(t, tmp, map[string]string{
+				"site.jsonr":          `{}`,
+				"templates/base.html": `{{.Page.Summary}}|{{.Content}}`,
+				"content/" + tc.file:  tc.body,
+			})
+
+			opts := &Options{&RawOptions{
+				SiteDir:  tmp,
+				Markdown: MarkdownConfig{Unsafe: ptr(tc.unsafe)},
+			}}
+			if err := renderContent(opts); err != nil {
+				t.Fatal(err)
+			}
+
+			expected := tc.summary + "|" + tc.content
+			if out := readFile(t, filepath.Join(tmp, "public/page.html")); out != expected {
+				t.Fatalf("output diff:\n%s", strDiff(expected, out))
+			}
+		})
+	}
+}
+
+func TestSplitSummary(t *testing.T) {
+	md := goldmark.New()
+	for name, tc := range map[string]struct {
+		body     string
+		summary  string // rendered summary, or "" for no split
+		warnings int
+	}{
+		"block":  {"Intro.\n\n<!--more-->\n\nRest.\n", "<p>Intro.</p>\n", 0},
+		"none":   {"Intro.\n\nRest.\n", "", 0},
+		"inline": {"Intro <!--more--> text.\n\nRest.\n", "", 1},
+		"both":   {"Intro <!--more--> text.\n<!--more-->\nRest.\n", "<p>Intro <!-- raw HTML omitted --> text.</p>\n", 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			src := []byte(tc.body)
+			doc := md.Parser().Parse(text.NewReader(src)).(*ast.Document)
+			warnings := []string{}
+			summaryDoc := splitSummary(doc, src, func(msg string) { warnings = append(warnings, msg) })
+
+			summary := ""
+			if summaryDoc != nil {
+				buf := &bytes.Buffer{}
+				if err := md.Renderer().Render(buf, src, summaryDoc); err != nil {
+					t.Fatal(err)
+				}
+				summary = buf.String()
+			}
+			if summary != tc.summary {
+				t.Errorf("expected summary %q, got %q", tc.summary, summary)
+			}
+			expected := slices.Repeat([]string{"<!--more--> within a paragraph is ignored, put it on a line of its own"}, tc.warnings)
+			if !slices.Equal(expected, warnings) {
+				t.Errorf("expected warnings %q, got %q", expected, warnings)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package build
 import (
 	"fmt"
 	"html/template"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
@@ -11,6 +12,12 @@ import (
 type TemplateLoader struct {
 	TemplateDir string
 	StaticDir   string
+	// BuiltinFS holds templates that are loaded first so the site can
+	// override them.
+	BuiltinFS fs.FS
+	// Strict makes references to missing map keys fail rather than render
+	// as "<no value>".
+	Strict bool
 }
 
 func (tl *TemplateLoader) Load() (*template.Template, error) {
@@ -21,6 +28,9 @@ func (tl *TemplateLoader) Load() (*template.Template, error) {
 			"htmlComment": func(s template.HTML) template.HTML { return template.HTML("<!--\n" + s + "\n-->") },
 			"jsonify":     jsonify,
 		})
+	if tl.Strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
 
 	maybeAddTemplate := func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -107,6 +117,28 @@ func (tl *TemplateLoader) Load() (*template.Template, error) {
 		}
 	}
 
+	if tl.BuiltinFS != nil {
+		err := fs.WalkDir(tl.BuiltinFS, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			b, err := fs.ReadFile(tl.BuiltinFS, path)
+			if err != nil {
+				return err
+			}
+			if _, err := tmpl.New(path).Parse(string(b)); err != nil {
+				return fmt.Errorf("failed parsing built-in %s: %w", path, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	err := filepath.Walk(tl.TemplateDir, maybeAddTemplate)
 	if err != nil {
 		return nil, err
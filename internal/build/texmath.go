@@ -0,0 +1,696 @@
+package build
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// texToMathML converts TeX math to MathML. It covers a practical subset of
+// TeX: letters, numbers and operators, scripts, groups, Greek letters and
+// symbols, \frac, \sqrt, \binom, fonts like \mathbb, \text, accents,
+// \left...\right and environments like matrix, cases and aligned. The
+// output is always usable; anything unsupported is rendered as <merror> and
+// described in problems.
+func texToMathML(tex string, display bool) (mathML string, problems []string) {
+	p := &texParser{toks: tokenizeTeX(tex), display: display}
+	body := texRow(p.parseList())
+	for p.pos < len(p.toks) {
+		// Only a stray } stops the top level.
+		p.problem("unexpected %s", p.toks[p.pos])
+		p.pos++
+		body = texRow([]string{body, texRow(p.parseList())})
+	}
+
+	attr := ""
+	if display {
+		attr = ` display="block"`
+	}
+	return `<math` + attr + `><semantics>` + body +
+		`<annotation encoding="application/x-tex">` + html.EscapeString(tex) + `</annotation>` +
+		`</semantics></math>`, p.problems
+}
+
+// tokenizeTeX splits TeX into commands like \alpha or \{, single characters,
+// and single spaces standing in for runs of whitespace. Comments are dropped.
+func tokenizeTeX(s string) []string {
+	toks := []string{}
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '\\' && i+1 < len(s):
+			j := i + 1
+			for j < len(s) && isASCIILetter(s[j]) {
+				j++
+			}
+			if j == i+1 {
+				_, n := utf8.DecodeRuneInString(s[j:])
+				j += n
+			}
+			toks = append(toks, s[i:j])
+			i = j
+		case r == '%':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case unicode.IsSpace(r):
+			if len(toks) == 0 || toks[len(toks)-1] != " " {
+				toks = append(toks, " ")
+			}
+			i += size
+		default:
+			toks = append(toks, s[i:i+size])
+			i += size
+		}
+	}
+	return toks
+}
+
+func isASCIILetter(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+type texParser struct {
+	toks     []string
+	pos      int
+	display  bool
+	font     string // from commands like \mathbb, see texFonts
+	problems []string
+}
+
+func (p *texParser) problem(format string, args ...any) {
+	p.problems = append(p.problems, fmt.Sprintf(format, args...))
+}
+
+// peek returns the next token that isn't a space.
+func (p *texParser) peek() (string, bool) {
+	for p.pos < len(p.toks) && p.toks[p.pos] == " " {
+		p.pos++
+	}
+	if p.pos >= len(p.toks) {
+		return "", false
+	}
+	return p.toks[p.pos], true
+}
+
+func (p *texParser) next() (string, bool) {
+	tok, ok := p.peek()
+	if ok {
+		p.pos++
+	}
+	return tok, ok
+}
+
+func (p *texParser) expect(tok string) {
+	if next, ok := p.peek(); ok && next == tok {
+		p.pos++
+		return
+	}
+	p.problem("missing %s", tok)
+}
+
+// parseList parses atoms with their scripts up to one of the stop tokens,
+// or a } that closes an enclosing group.
+func (p *texParser) parseList(stops ...string) []string {
+	items := []string{}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok == "}" || slices.Contains(stops, tok) {
+			return items
+		}
+		base, limits := "<mrow></mrow>", false
+		if tok != "^" && tok != "_" {
+			base, limits = p.parseAtom(true)
+			if base == "" {
+				continue
+			}
+		}
+		items = append(items, p.parseScripts(base, limits))
+	}
+}
+
+// parseScripts attaches any ^ and _ that follow base. Scripts go above and
+// below operators like \sum in display math.
+func (p *texParser) parseScripts(base string, limits bool) string {
+	sub, sup := "", ""
+	for {
+		tok, _ := p.peek()
+		if tok == "^" && sup == "" {
+			p.pos++
+			sup = p.parseArg()
+		} else if tok == "_" && sub == "" {
+			p.pos++
+			sub = p.parseArg()
+		} else {
+			break
+		}
+	}
+	under, over, both := "msub", "msup", "msubsup"
+	if limits && p.display {
+		under, over, both = "munder", "mover", "munderover"
+	}
+	switch {
+	case sub != "" && sup != "":
+		return "<" + both + ">" + base + sub + sup + "</" + both + ">"
+	case sub != "":
+		return "<" + under + ">" + base + sub + "</" + under + ">"
+	case sup != "":
+		return "<" + over + ">" + base + sup + "</" + over + ">"
+	}
+	return base
+}
+
+// parseArg parses a command argument: a group, or a single token.
+func (p *texParser) parseArg() string {
+	tok, ok := p.peek()
+	if !ok || tok == "}" {
+		p.problem("missing argument")
+		return "<mrow></mrow>"
+	}
+	for {
+		arg, _ := p.parseAtom(false)
+		if arg != "" {
+			return arg
+		}
+		if tok, ok := p.peek(); !ok || tok == "}" {
+			return "<mrow></mrow>"
+		}
+	}
+}
+
+// rawArg returns the text of a group argument, for \text and environment
+// names.
+func (p *texParser) rawArg() string {
+	if tok, ok := p.peek(); !ok || tok != "{" {
+		p.problem("missing {")
+		return ""
+	}
+	p.pos++
+	b := strings.Builder{}
+	for depth := 0; p.pos < len(p.toks); p.pos++ {
+		tok := p.toks[p.pos]
+		switch tok {
+		case "{":
+			depth++
+		case "}":
+			if depth == 0 {
+				p.pos++
+				return b.String()
+			}
+			depth--
+		}
+		if len(tok) == 2 && tok[0] == '\\' && strings.ContainsRune(`{}$%&#_ `, rune(tok[1])) {
+			tok = tok[1:]
+		}
+		b.WriteString(tok)
+	}
+	p.problem("missing }")
+	return b.String()
+}
+
+// parseAtom parses one token, or a command with its arguments. Consecutive
+// digits make one number if merge is set. It returns "" for tokens that
+// produce nothing, and whether scripts go above and below in display math.
+func (p *texParser) parseAtom(merge bool) (string, bool) {
+	tok, _ := p.next()
+	r, _ := utf8.DecodeRuneInString(tok)
+	switch {
+	case tok == "{":
+		items := p.parseList()
+		p.expect("}")
+		return texRow(items), false
+	case tok == "}":
+		p.problem("unexpected }")
+		return "", false
+	case tok == "&" || tok == `\\`:
+		// Only meaningful in environments.
+		return "", false
+	case tok == "~":
+		return `<mspace width="0.333em"></mspace>`, false
+	case tok == "^" || tok == "_":
+		p.pos--
+		return "<mrow></mrow>", false
+	case strings.HasPrefix(tok, `\`) && len(tok) > 1:
+		return p.parseCommand(tok[1:])
+	case unicode.IsDigit(r):
+		num := tok
+		for merge && p.pos < len(p.toks) {
+			next := p.toks[p.pos]
+			if next == "." && p.pos+1 < len(p.toks) && isDigitToken(p.toks[p.pos+1]) || isDigitToken(next) {
+				num += next
+				p.pos++
+				continue
+			}
+			break
+		}
+		return "<mn>" + html.EscapeString(p.withFont(num)) + "</mn>", false
+	case unicode.IsLetter(r):
+		if p.font == "normal" {
+			// \mathrm{sin} is one upright word.
+			for merge && p.pos < len(p.toks) && isLetterToken(p.toks[p.pos]) {
+				tok += p.toks[p.pos]
+				p.pos++
+			}
+			if utf8.RuneCountInString(tok) == 1 {
+				return `<mi mathvariant="normal">` + html.EscapeString(tok) + "</mi>", false
+			}
+			return "<mi>" + html.EscapeString(tok) + "</mi>", false
+		}
+		return "<mi>" + html.EscapeString(p.withFont(tok)) + "</mi>", false
+	}
+	if op, ok := texCharOperators[tok]; ok {
+		tok = op
+	}
+	return "<mo>" + html.EscapeString(tok) + "</mo>", false
+}
+
+func isDigitToken(tok string) bool {
+	return len(tok) == 1 && '0' <= tok[0] && tok[0] <= '9'
+}
+
+func isLetterToken(tok string) bool {
+	r, size := utf8.DecodeRuneInString(tok)
+	return size == len(tok) && unicode.IsLetter(r)
+}
+
+// parseCommand parses a command without its backslash.
+func (p *texParser) parseCommand(name string) (string, bool) {
+	if s, ok := texIdentifiers[name]; ok {
+		return "<mi>" + s + "</mi>", false
+	}
+	if s, ok := texUprightIdentifiers[name]; ok {
+		return `<mi mathvariant="normal">` + s + "</mi>", false
+	}
+	if s, ok := texOperators[name]; ok {
+		return "<mo>" + html.EscapeString(s) + "</mo>", false
+	}
+	if op, ok := texLargeOperators[name]; ok {
+		return `<mo largeop="true" movablelimits="` + fmt.Sprint(op.limits) + `">` + op.symbol + "</mo>", op.limits
+	}
+	if limits, ok := texFunctions[name]; ok {
+		return "<mi>" + name + "</mi>", limits
+	}
+	if width, ok := texSpaces[name]; ok {
+		return `<mspace width="` + width + `"></mspace>`, false
+	}
+	if font, ok := texFonts[name]; ok {
+		outer := p.font
+		p.font = font
+		arg := p.parseArg()
+		p.font = outer
+		return arg, false
+	}
+	if accent, ok := texAccents[name]; ok {
+		arg := p.parseArg()
+		if accent.under {
+			return `<munder accentunder="true">` + arg + `<mo stretchy="true">` + accent.symbol + "</mo></munder>", accent.limits
+		}
+		return `<mover accent="true">` + arg + `<mo stretchy="true">` + accent.symbol + "</mo></mover>", accent.limits
+	}
+	if size, ok := texDelimiterSizes[name]; ok {
+		delim := p.delimiter()
+		return `<mo fence="false" stretchy="true" minsize="` + size + `" maxsize="` + size + `">` + delim + "</mo>", false
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac", "cfrac":
+		num := p.parseArg()
+		den := p.parseArg()
+		return "<mfrac>" + num + den + "</mfrac>", false
+	case "binom":
+		n := p.parseArg()
+		k := p.parseArg()
+		return `<mrow><mo>(</mo><mfrac linethickness="0">` + n + k + `</mfrac><mo>)</mo></mrow>`, false
+	case "sqrt":
+		index := ""
+		if tok, _ := p.peek(); tok == "[" {
+			p.pos++
+			index = texRow(p.parseList("]"))
+			p.expect("]")
+		}
+		arg := p.parseArg()
+		if index != "" {
+			return "<mroot>" + arg + index + "</mroot>", false
+		}
+		return "<msqrt>" + arg + "</msqrt>", false
+	case "text", "textrm", "textit", "textbf", "textsf", "texttt", "textnormal", "mbox":
+		return "<mtext>" + html.EscapeString(p.rawArg()) + "</mtext>", false
+	case "operatorname":
+		return "<mi>" + html.EscapeString(p.rawArg()) + "</mi>", false
+	case "overset", "stackrel", "underset":
+		over := p.parseArg()
+		base := p.parseArg()
+		if name == "underset" {
+			return "<munder>" + base + over + "</munder>", false
+		}
+		return "<mover>" + base + over + "</mover>", false
+	case "left":
+		open := p.delimiter()
+		items := p.parseList(`\right`)
+		close := ""
+		if tok, _ := p.peek(); tok == `\right` {
+			p.pos++
+			close = p.delimiter()
+		} else {
+			p.problem(`missing \right`)
+		}
+		return `<mrow><mo fence="true" stretchy="true">` + open + "</mo>" + strings.Join(items, "") +
+			`<mo fence="true" stretchy="true">` + close + "</mo></mrow>", false
+	case "middle":
+		return `<mo stretchy="true">` + p.delimiter() + "</mo>", false
+	case "right":
+		p.problem(`\right without \left`)
+		p.delimiter()
+		return "", false
+	case "begin":
+		return p.parseEnvironment(), false
+	case "end":
+		p.problem(`\end{%s} without \begin`, p.rawArg())
+		return "", false
+	case "not":
+		tok, _ := p.next()
+		switch tok {
+		case "=":
+			return "<mo>≠</mo>", false
+		case `\in`:
+			return "<mo>∉</mo>", false
+		}
+		if s, ok := texOperators[strings.TrimPrefix(tok, `\`)]; ok {
+			tok = s
+		}
+		return "<mo>" + html.EscapeString(tok) + "̸</mo>", false
+	case "bmod":
+		return "<mo>mod</mo>", false
+	case "pmod":
+		arg := p.parseArg()
+		return `<mrow><mo>(</mo><mi>mod</mi><mspace width="0.333em"></mspace>` + arg + "<mo>)</mo></mrow>", false
+	case "displaystyle", "textstyle", "scriptstyle", "limits", "nolimits":
+		return "", false
+	case "{", "}":
+		return "<mo>" + name + "</mo>", false
+	case "|":
+		return "<mo>‖</mo>", false
+	case "$", "%", "#", "&", "_":
+		return "<mtext>" + html.EscapeString(name) + "</mtext>", false
+	}
+
+	p.problem(`unsupported TeX command \%s`, name)
+	return `<merror><mtext>\` + html.EscapeString(name) + "</mtext></merror>", false
+}
+
+// delimiter reads the delimiter after \left, \right or \big. A period is
+// an empty delimiter.
+func (p *texParser) delimiter() string {
+	tok, ok := p.next()
+	switch {
+	case !ok:
+		p.problem("missing delimiter")
+		return ""
+	case tok == ".":
+		return ""
+	case strings.HasPrefix(tok, `\`):
+		if s, ok := texDelimiters[tok[1:]]; ok {
+			return s
+		}
+		p.problem("unsupported delimiter %s", tok)
+		return ""
+	}
+	return html.EscapeString(tok)
+}
+
+// parseEnvironment parses the rows of \begin{name}...\end{name} into a
+// table.
+func (p *texParser) parseEnvironment() string {
+	name := p.rawArg()
+	env, ok := texEnvironments[name]
+	if !ok {
+		p.problem("unsupported environment %s", name)
+	}
+	if name == "array" {
+		p.rawArg() // column spec
+	}
+
+	rows := [][]string{{}}
+	for {
+		cell := p.parseList("&", `\\`, `\end`)
+		row := &rows[len(rows)-1]
+		*row = append(*row, texRow(cell))
+		tok, ok := p.next()
+		if !ok || tok == "}" {
+			if tok == "}" {
+				p.pos--
+			}
+			p.problem(`missing \end{%s}`, name)
+			break
+		}
+		if tok == `\\` {
+			if next, _ := p.peek(); next == "[" {
+				// Row spacing like \\[2pt].
+				for p.pos < len(p.toks) && p.toks[p.pos] != "]" {
+					p.pos++
+				}
+				p.pos++
+			}
+			rows = append(rows, []string{})
+		}
+		if tok == `\end` {
+			if end := p.rawArg(); end != name {
+				p.problem(`\begin{%s} ended by \end{%s}`, name, end)
+			}
+			break
+		}
+	}
+	// A trailing \\ doesn't start a row.
+	if last := rows[len(rows)-1]; len(rows) > 1 && len(last) == 1 && last[0] == "<mrow></mrow>" {
+		rows = rows[:len(rows)-1]
+	}
+
+	b := strings.Builder{}
+	b.WriteString("<mtable")
+	if env.align != "" {
+		b.WriteString(` columnalign="` + env.align + `"`)
+	}
+	if env.displaystyle {
+		b.WriteString(` displaystyle="true"`)
+	}
+	b.WriteString(">")
+	for _, row := range rows {
+		b.WriteString("<mtr>")
+		for _, cell := range row {
+			b.WriteString("<mtd>" + cell + "</mtd>")
+		}
+		b.WriteString("</mtr>")
+	}
+	b.WriteString("</mtable>")
+	if env.open == "" && env.close == "" {
+		return b.String()
+	}
+	table := `<mrow><mo fence="true">` + env.open + "</mo>" + b.String()
+	if env.close != "" {
+		table += `<mo fence="true">` + env.close + "</mo>"
+	}
+	return table + "</mrow>"
+}
+
+// withFont maps ASCII letters and digits to the current font's Unicode
+// mathematical alphanumerics.
+func (p *texParser) withFont(s string) string {
+	alphabet, ok := texAlphabets[p.font]
+	if !ok {
+		return s
+	}
+	b := strings.Builder{}
+	for _, r := range s {
+		if ex, ok := alphabet.exceptions[r]; ok {
+			b.WriteRune(ex)
+			continue
+		}
+		switch {
+		case 'A' <= r && r <= 'Z':
+			b.WriteRune(alphabet.upper + r - 'A')
+		case 'a' <= r && r <= 'z':
+			b.WriteRune(alphabet.lower + r - 'a')
+		case '0' <= r && r <= '9' && alphabet.digit != 0:
+			b.WriteRune(alphabet.digit + r - '0')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// texRow joins elements into one.
+func texRow(items []string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return "<mrow>" + strings.Join(items, "") + "</mrow>"
+}
+
+var texCharOperators = map[string]string{
+	"-": "−",
+	"*": "∗",
+	"'": "′",
+}
+
+var texIdentifiers = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ",
+	"varepsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ",
+	"iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ",
+	"pi": "π", "varpi": "ϖ", "rho": "ρ", "varrho": "ϱ", "sigma": "σ",
+	"varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ", "varphi": "φ",
+	"chi": "χ", "psi": "ψ", "omega": "ω",
+	"ell": "ℓ", "hbar": "ℏ", "imath": "ı", "jmath": "ȷ", "wp": "℘",
+}
+
+var texUprightIdentifiers = map[string]string{
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ",
+	"Pi": "Π", "Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ",
+	"Omega": "Ω",
+	"infty": "∞", "partial": "∂", "nabla": "∇", "emptyset": "∅",
+	"varnothing": "∅", "aleph": "ℵ", "Re": "ℜ", "Im": "ℑ", "top": "⊤",
+	"bot": "⊥", "angle": "∠", "triangle": "△",
+}
+
+var texOperators = map[string]string{
+	"times": "×", "cdot": "⋅", "pm": "±", "mp": "∓", "div": "÷", "ast": "∗",
+	"star": "⋆", "circ": "∘", "bullet": "∙", "oplus": "⊕", "ominus": "⊖",
+	"otimes": "⊗", "odot": "⊙", "setminus": "∖", "wedge": "∧", "land": "∧",
+	"vee": "∨", "lor": "∨", "neg": "¬", "lnot": "¬", "cup": "∪", "cap": "∩",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠",
+	"ll": "≪", "gg": "≫", "approx": "≈", "equiv": "≡", "sim": "∼",
+	"simeq": "≃", "cong": "≅", "propto": "∝", "doteq": "≐", "prec": "≺",
+	"succ": "≻", "preceq": "⪯", "succeq": "⪰", "perp": "⊥", "parallel": "∥",
+	"mid": "∣", "in": "∈", "notin": "∉", "ni": "∋", "subset": "⊂",
+	"supset": "⊃", "subseteq": "⊆", "supseteq": "⊇", "models": "⊨",
+	"vdash": "⊢", "to": "→", "rightarrow": "→", "leftarrow": "←",
+	"gets": "←", "leftrightarrow": "↔", "Rightarrow": "⇒",
+	"Leftarrow": "⇐", "Leftrightarrow": "⇔", "implies": "⟹",
+	"impliedby": "⟸", "iff": "⟺", "mapsto": "↦",
+	"longrightarrow": "⟶", "longleftarrow": "⟵", "uparrow": "↑",
+	"downarrow": "↓", "forall": "∀", "exists": "∃", "nexists": "∄",
+	"ldots": "…", "dots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱",
+	"prime": "′", "colon": ":", "langle": "⟨", "rangle": "⟩",
+	"lfloor": "⌊", "rfloor": "⌋", "lceil": "⌈", "rceil": "⌉",
+	"vert": "|", "Vert": "‖", "backslash": "\\", "dagger": "†",
+	"therefore": "∴", "because": "∵",
+}
+
+type texLargeOperator struct {
+	symbol string
+	limits bool
+}
+
+var texLargeOperators = map[string]texLargeOperator{
+	"sum": {"∑", true}, "prod": {"∏", true}, "coprod": {"∐", true},
+	"bigcup": {"⋃", true}, "bigcap": {"⋂", true}, "bigoplus": {"⨁", true},
+	"bigotimes": {"⨂", true}, "bigvee": {"⋁", true}, "bigwedge": {"⋀", true},
+	"int": {"∫", false}, "iint": {"∬", false}, "iiint": {"∭", false},
+	"oint": {"∮", false},
+}
+
+// texFunctions are upright operator names, and whether their scripts go
+// above and below in display math.
+var texFunctions = map[string]bool{
+	"sin": false, "cos": false, "tan": false, "cot": false, "sec": false,
+	"csc": false, "arcsin": false, "arccos": false, "arctan": false,
+	"sinh": false, "cosh": false, "tanh": false, "log": false, "ln": false,
+	"lg": false, "exp": false, "arg": false, "deg": false, "dim": false,
+	"hom": false, "ker": false,
+	"lim": true, "liminf": true, "limsup": true, "max": true, "min": true,
+	"sup": true, "inf": true, "det": true, "gcd": true, "Pr": true,
+}
+
+var texSpaces = map[string]string{
+	",": "0.1667em", ":": "0.2222em", ">": "0.2222em", ";": "0.2778em",
+	"!": "-0.1667em", " ": "0.333em", "quad": "1em", "qquad": "2em",
+}
+
+// texFonts map font commands to texAlphabets, or "normal" for upright.
+var texFonts = map[string]string{
+	"mathrm": "normal", "mathup": "normal", "mathbf": "bold",
+	"boldsymbol": "bold", "bm": "bold", "mathit": "italic",
+	"mathcal": "script", "mathscr": "script", "mathfrak": "fraktur",
+	"mathbb": "double-struck", "mathsf": "sans-serif", "mathtt": "monospace",
+}
+
+type texAlphabet struct {
+	upper, lower, digit rune
+	exceptions          map[rune]rune // letters that predate the block
+}
+
+var texAlphabets = map[string]texAlphabet{
+	"bold":   {0x1D400, 0x1D41A, 0x1D7CE, nil},
+	"italic": {0x1D434, 0x1D44E, 0, map[rune]rune{'h': 'ℎ'}},
+	"script": {0x1D49C, 0x1D4B6, 0, map[rune]rune{
+		'B': 'ℬ', 'E': 'ℰ', 'F': 'ℱ', 'H': 'ℋ', 'I': 'ℐ', 'L': 'ℒ', 'M': 'ℳ',
+		'R': 'ℛ', 'e': 'ℯ', 'g': 'ℊ', 'o': 'ℴ',
+	}},
+	"fraktur": {0x1D504, 0x1D51E, 0, map[rune]rune{
+		'C': 'ℭ', 'H': 'ℌ', 'I': 'ℑ', 'R': 'ℜ', 'Z': 'ℨ',
+	}},
+	"double-struck": {0x1D538, 0x1D552, 0x1D7D8, map[rune]rune{
+		'C': 'ℂ', 'H': 'ℍ', 'N': 'ℕ', 'P': 'ℙ', 'Q': 'ℚ', 'R': 'ℝ', 'Z': 'ℤ',
+	}},
+	"sans-serif": {0x1D5A0, 0x1D5BA, 0x1D7E2, nil},
+	"monospace":  {0x1D670, 0x1D68A, 0x1D7F6, nil},
+}
+
+type texAccent struct {
+	symbol string
+	under  bool
+	limits bool // scripts go above or below, as with \overbrace
+}
+
+var texAccents = map[string]texAccent{
+	"hat": {"^", false, false}, "widehat": {"^", false, false},
+	"bar": {"¯", false, false}, "overline": {"‾", false, false},
+	"vec": {"→", false, false}, "overrightarrow": {"→", false, false},
+	"dot": {"˙", false, false}, "ddot": {"¨", false, false},
+	"tilde": {"~", false, false}, "widetilde": {"~", false, false},
+	"check": {"ˇ", false, false}, "breve": {"˘", false, false},
+	"acute": {"´", false, false}, "grave": {"`", false, false},
+	"underline": {"_", true, false},
+	"overbrace": {"⏞", false, true}, "underbrace": {"⏟", true, true},
+}
+
+var texDelimiters = map[string]string{
+	"{": "{", "}": "}", "|": "‖", "langle": "⟨", "rangle": "⟩",
+	"lfloor": "⌊", "rfloor": "⌋", "lceil": "⌈", "rceil": "⌉",
+	"vert": "|", "Vert": "‖", "lvert": "|", "rvert": "|", "lVert": "‖",
+	"rVert": "‖", "uparrow": "↑", "downarrow": "↓", "backslash": "\\",
+	"lbrace": "{", "rbrace": "}",
+}
+
+var texDelimiterSizes = map[string]string{
+	"big": "1.2em", "bigl": "1.2em", "bigr": "1.2em", "bigm": "1.2em",
+	"Big": "1.8em", "Bigl": "1.8em", "Bigr": "1.8em", "Bigm": "1.8em",
+	"bigg": "2.4em", "biggl": "2.4em", "biggr": "2.4em", "biggm": "2.4em",
+	"Bigg": "3em", "Biggl": "3em", "Biggr": "3em", "Biggm": "3em",
+}
+
+type texEnvironment struct {
+	open, close  string
+	align        string
+	displaystyle bool
+}
+
+var texEnvironments = map[string]texEnvironment{
+	"matrix":      {},
+	"smallmatrix": {},
+	"array":       {},
+	"pmatrix":     {open: "(", close: ")"},
+	"bmatrix":     {open: "[", close: "]"},
+	"Bmatrix":     {open: "{", close: "}"},
+	"vmatrix":     {open: "|", close: "|"},
+	"Vmatrix":     {open: "‖", close: "‖"},
+	"cases":       {open: "{", align: "left left"},
+	"aligned":     {align: "right left", displaystyle: true},
+	"align":       {align: "right left", displaystyle: true},
+	"align*":      {align: "right left", displaystyle: true},
+	"split":       {align: "right left", displaystyle: true},
+	"gathered":    {displaystyle: true},
+}
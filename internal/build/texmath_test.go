@@ -0,0 +1,42 @@
+package build
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTeXToMathML(t *testing.T) {
+	for _, tc := range []struct {
+		tex      string
+		display  bool
+		expected string
+		problems []string
+	}{
+		{`a_b * c`, false, `<mrow><msub><mi>a</mi><mi>b</mi></msub><mo>∗</mo><mi>c</mi></mrow>`, nil},
+		{`x^2 + y_{i}^{n+1} = 3.14`, false, `<mrow><msup><mi>x</mi><mn>2</mn></msup><mo>+</mo><msubsup><mi>y</mi><mi>i</mi><mrow><mi>n</mi><mo>+</mo><mn>1</mn></mrow></msubsup><mo>=</mo><mn>3.14</mn></mrow>`, nil},
+		{`\frac12 \sqrt[3]{x}`, false, `<mrow><mfrac><mn>1</mn><mn>2</mn></mfrac><mroot><mi>x</mi><mn>3</mn></mroot></mrow>`, nil},
+		{`\sum_{i=1}^n i`, false, `<mrow><msubsup><mo largeop="true" movablelimits="true">∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></msubsup><mi>i</mi></mrow>`, nil},
+		{`\sum_{i=1}^n i`, true, `<mrow><munderover><mo largeop="true" movablelimits="true">∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></munderover><mi>i</mi></mrow>`, nil},
+		{`\lim_{x \to 0} \sin x`, true, `<mrow><munder><mi>lim</mi><mrow><mi>x</mi><mo>→</mo><mn>0</mn></mrow></munder><mi>sin</mi><mi>x</mi></mrow>`, nil},
+		{`\alpha \Gamma \mathbb{R} \mathbf{v} \mathrm{d}x`, false, `<mrow><mi>α</mi><mi mathvariant="normal">Γ</mi><mi>ℝ</mi><mi>𝐯</mi><mi mathvariant="normal">d</mi><mi>x</mi></mrow>`, nil},
+		{`\left\langle a \middle| b \right.`, false, `<mrow><mo fence="true" stretchy="true">⟨</mo><mi>a</mi><mo stretchy="true">|</mo><mi>b</mi><mo fence="true" stretchy="true"></mo></mrow>`, nil},
+		{`\hat{x} \text{if } a < b`, false, `<mrow><mover accent="true"><mi>x</mi><mo stretchy="true">^</mo></mover><mtext>if </mtext><mi>a</mi><mo>&lt;</mo><mi>b</mi></mrow>`, nil},
+		{`\begin{cases} 1 & x > 0 \\ 0 & \text{else} \end{cases}`, false, `<mrow><mo fence="true">{</mo><mtable columnalign="left left"><mtr><mtd><mn>1</mn></mtd><mtd><mrow><mi>x</mi><mo>&gt;</mo><mn>0</mn></mrow></mtd></mtr><mtr><mtd><mn>0</mn></mtd><mtd><mtext>else</mtext></mtd></mtr></mtable></mrow>`, nil},
+		{`\begin{pmatrix} a & b \\ c & d \\ \end{pmatrix}`, false, `<mrow><mo fence="true">(</mo><mtable><mtr><mtd><mi>a</mi></mtd><mtd><mi>b</mi></mtd></mtr><mtr><mtd><mi>c</mi></mtd><mtd><mi>d</mi></mtd></mtr></mtable><mo fence="true">)</mo></mrow>`, nil},
+		{`\foo x`, false, `<mrow><merror><mtext>\foo</mtext></merror><mi>x</mi></mrow>`, []string{`unsupported TeX command \foo`}},
+		{`{a`, false, `<mi>a</mi>`, []string{"missing }"}},
+		{`\begin{matrix} a \end{bmatrix}`, false, `<mtable><mtr><mtd><mi>a</mi></mtd></mtr></mtable>`, []string{`\begin{matrix} ended by \end{bmatrix}`}},
+	} {
+		out, problems := texToMathML(tc.tex, tc.display)
+		body := strings.TrimPrefix(out, "<math><semantics>")
+		body = strings.TrimPrefix(body, `<math display="block"><semantics>`)
+		body, _, _ = strings.Cut(body, "<annotation")
+		if body != tc.expected {
+			t.Errorf("%s:\nexpected %s\ngot      %s", tc.tex, tc.expected, body)
+		}
+		if !reflect.DeepEqual(problems, tc.problems) {
+			t.Errorf("%s: expected problems %q, got %q", tc.tex, tc.problems, problems)
+		}
+	}
+}
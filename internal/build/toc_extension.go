@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"html/template"
 	"log"
+	"strings"
 
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/ast"
@@ -57,7 +58,9 @@ func (t *tocTransformer) Transform(node *ast.Document, reader text.Reader, pc pa
 			return ast.WalkContinue, nil
 		}
 
-		text := extractText(heading, reader.Source())
+		// Shortcode output isn't known yet, so leave it out.
+		text := shortcodePlaceholder.ReplaceAllString(extractText(heading, reader.Source()), "")
+		text = strings.TrimSpace(text)
 
 		// Get heading ID - this needs to be enabled
 		id := ""
@@ -0,0 +1,86 @@
+package build
+
+import (
+	"path"
+	"path/filepath"
+	"slices"
+)
+
+// rootTitle names the content root in breadcrumbs when it has no index page
+// with a title.
+const rootTitle = "Home"
+
+// isIndex reports whether a page is the index page of its directory.
+func (p *Page) isIndex() bool {
+	return p.File.BaseName == "index"
+}
+
+// LinkTitle is the title to show in links to the page, such as breadcrumbs.
+// It is Title, or without one, the directory name for an index page and the
+// file name for any other page.
+func (p *Page) LinkTitle() string {
+	if p.Title != "" {
+		return p.Title
+	}
+	if p.dirTitle != "" {
+		return p.dirTitle
+	}
+	return p.File.BaseName
+}
+
+// dirTitle names a directory, given as a slash-separated path relative to
+// ContentDir, for pages without a title.
+func dirTitle(dir string) string {
+	if dir == "." {
+		return rootTitle
+	}
+	return path.Base(dir)
+}
+
+// dirPage returns the page standing in for a content directory, given as a
+// slash-separated path relative to ContentDir. This is the directory's index
+// page if it has one, otherwise an empty page. Either way, LinkTitle falls
+// back to the directory name.
+func (s *site) dirPage(dir string) *Page {
+	if p, ok := s.dirPages[dir]; ok {
+		return p
+	}
+
+	var page *Page
+	for _, name := range []string{"index.md", "index.html"} {
+		if p, ok := s.byPath[filepath.FromSlash(path.Join(dir, name))]; ok {
+			page = p
+			break
+		}
+	}
+	if page == nil {
+		page = &Page{Params: map[string]any{}, dirTitle: dirTitle(dir)}
+	}
+	s.dirPages[dir] = page
+	return page
+}
+
+// setAncestors sets Parent and Ancestors from the directory structure. An
+// index page's ancestors start with the directory above its own.
+func (s *site) setAncestors(p *Page) {
+	dir := path.Dir(p.File.Path)
+	if p.isIndex() {
+		if dir == "." {
+			return
+		}
+		dir = path.Dir(dir)
+	}
+
+	ancestors := Pages{}
+	for {
+		ancestors = append(ancestors, s.dirPage(dir))
+		if dir == "." {
+			break
+		}
+		dir = path.Dir(dir)
+	}
+	slices.Reverse(ancestors)
+
+	p.Ancestors = ancestors
+	p.Parent = ancestors[len(ancestors)-1]
+}
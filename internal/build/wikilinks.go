@@ -0,0 +1,171 @@
+package build
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// WikiIndex finds pages by the names a wiki link can use. Names are matched
+// case-insensitively.
+type WikiIndex struct {
+	// In order of precedence: the path relative to ContentDir with or
+	// without its extension, the file name without extension, the
+	// frontmatter Title, and the frontmatter Aliases.
+	tiers [4]map[string]Pages
+}
+
+func NewWikiIndex(pages Pages) *WikiIndex {
+	idx := &WikiIndex{}
+	for i := range idx.tiers {
+		idx.tiers[i] = map[string]Pages{}
+	}
+	add := func(tier int, name string, p *Page) {
+		key := wikiKey(name)
+		if key == "" {
+			return
+		}
+		for _, q := range idx.tiers[tier][key] {
+			if q == p {
+				return
+			}
+		}
+		idx.tiers[tier][key] = append(idx.tiers[tier][key], p)
+	}
+	for _, p := range pages {
+		rel := filepath.ToSlash(p.relPath)
+		add(0, rel, p)
+		add(0, strings.TrimSuffix(rel, path.Ext(rel)), p)
+		add(1, p.File.BaseName, p)
+		add(2, p.Title, p)
+		for _, alias := range p.Aliases {
+			add(3, alias, p)
+		}
+	}
+	return idx
+}
+
+func wikiKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// Resolve returns the page a wiki link names. If several pages match at the
+// same level, the first in content order is returned along with all of them.
+func (idx *WikiIndex) Resolve(name string) (*Page, Pages) {
+	key := wikiKey(strings.TrimPrefix(name, "/"))
+	for _, tier := range idx.tiers {
+		if pages := tier[key]; len(pages) > 0 {
+			return pages[0], pages
+		}
+	}
+	return nil, nil
+}
+
+// WikiLinks parses [[target]], [[target|text]] and [[target#anchor|text]]
+// into links to the page found in Index. Links are given the target's
+// source path, so LinkRewriter turns them into output URLs and records them
+// as backlinks like any other Markdown link. Links to unknown pages are
+// reported and left as text.
+type WikiLinks struct {
+	Index *WikiIndex
+	Warn  func(msg string)
+}
+
+func (e WikiLinks) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(
+		// Before goldmark's link parser at 200.
+		util.Prioritized(wikiLinkParser{e}, 199),
+	))
+}
+
+// wikiLinksKey holds the set of links made by wikiLinkParser. Their
+// destinations are relative to the page, even in included Markdown.
+var wikiLinksKey = parser.NewContextKey()
+
+type wikiLinkParser struct {
+	WikiLinks
+}
+
+func (p wikiLinkParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+func (p wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, seg := block.PeekLine()
+	if !bytes.HasPrefix(line, []byte("[[")) {
+		return nil
+	}
+	end := bytes.Index(line, []byte("]]"))
+	if end < 0 {
+		return nil
+	}
+	inner := line[2:end]
+	if len(bytes.TrimSpace(inner)) == 0 || bytes.ContainsAny(inner, "[]\n") {
+		return nil
+	}
+
+	// The link text is the target as written, or what follows a |.
+	target := inner
+	labelStart := 2
+	if i := bytes.IndexByte(inner, '|'); i >= 0 {
+		target = inner[:i]
+		labelStart += i + 1
+	}
+	name, anchor := splitAnchor(string(target))
+	name = strings.TrimSpace(name)
+	anchor = strings.TrimSpace(anchor)
+
+	srcFile := pc.Get(SourceFileKey).(string)
+	dest := ""
+	if name == "" {
+		// [[#anchor]] links within the page.
+		if anchor == "" {
+			return nil
+		}
+	} else {
+		page, matches := p.Index.Resolve(name)
+		if page == nil {
+			p.Warn(fmt.Sprintf("broken wiki link → [[%s]]", target))
+			return nil
+		}
+		if len(matches) > 1 {
+			paths := []string{}
+			for _, m := range matches {
+				paths = append(paths, m.File.Path)
+			}
+			p.Warn(fmt.Sprintf("ambiguous wiki link → [[%s]] matches %s, using %s", target, strings.Join(paths, ", "), page.File.Path))
+		}
+		rel, err := filepath.Rel(filepath.Dir(srcFile), page.relPath)
+		if err != nil {
+			rel = "/" + page.relPath
+		}
+		dest = filepath.ToSlash(rel)
+	}
+
+	block.Advance(end + 2)
+	link := ast.NewLink()
+	link.Destination = []byte(withAnchor(dest, anchor))
+	start, stop := seg.Start+labelStart, seg.Start+end
+	for start < stop && util.IsSpace(line[start-seg.Start]) {
+		start++
+	}
+	for stop > start && util.IsSpace(line[stop-seg.Start-1]) {
+		stop--
+	}
+	link.AppendChild(link, ast.NewTextSegment(text.NewSegment(start, stop)))
+	links, _ := pc.Get(wikiLinksKey).(map[ast.Node]bool)
+	if links == nil {
+		links = map[ast.Node]bool{}
+		pc.Set(wikiLinksKey, links)
+	}
+	links[link] = true
+	return link
+}
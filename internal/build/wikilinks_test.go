@@ -0,0 +1,121 @@
+package build
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderContentWikiLinks(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{.Content}}back:{{range .Page.Backlinks}}[{{.Page.Title}}: {{.Text}}]{{end}}`,
+		"content/index.md": "---\n{\"Title\": \"Home\"}\n---\n" +
+			"[[Getting Started]], [[setup|the setup]], [[docs/setup#install]], [[Quick Start | quick]] and [[#top]].\n\n" +
+			"`[[Getting Started]]` and [not a wiki link](docs/setup.md).\n",
+		"content/docs/setup.md": "---\n{\"Title\": \"Getting Started\", \"Aliases\": [\"Quick Start\"]}\n---\n# Install\n\nBack [[home]].\n",
+		"content/off.md":        "---\n{\"Title\": \"Off\", \"Markdown\": {\"WikiLinks\": false}}\n---\n[[setup]]\n",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir:  tmp,
+		Markdown: MarkdownConfig{WikiLinks: ptr(true)},
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<p><a href="docs/setup.html">Getting Started</a>, <a href="docs/setup.html">the setup</a>, <a href="docs/setup.html#install">docs/setup#install</a>, <a href="docs/setup.html">quick</a> and <a href="#top">#top</a>.</p>
+<p><code>[[Getting Started]]</code> and <a href="docs/setup.html">not a wiki link</a>.</p>
+back:[Getting Started: home]`
+	if out := readFile(t, filepath.Join(tmp, "public/index.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+
+	expected = "<h1 id=\"install\">Install</h1>\n<p>Back <a href=\"../index.html\">home</a>.</p>\nback:[Home: Getting Started]"
+	if out := readFile(t, filepath.Join(tmp, "public/docs/setup.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+
+	expected = "<p>[[setup]]</p>\nback:"
+	if out := readFile(t, filepath.Join(tmp, "public/off.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+}
+
+func TestWikiLinkWarnings(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{.Content}}`,
+		"content/page.md":     "[[nowhere]] and [[note]].\n",
+		"content/a/note.md":   "",
+		"content/b/note.md":   "",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir:  tmp,
+		Strict:   true,
+		Markdown: MarkdownConfig{WikiLinks: ptr(true)},
+	}}
+	err := renderContent(opts)
+	if err == nil {
+		t.Fatal("expected warnings to fail a strict build")
+	}
+	for _, want := range []string{
+		"broken wiki link → [[nowhere]]",
+		"ambiguous wiki link → [[note]] matches a/note.md, b/note.md, using a/note.md",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestRenderContentWikiLinksDerivedTitle(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{.Page.Title}}:{{.Content}}`,
+		"content/a.md":        "See [[Zeta Guide]] and a[[0]].\n",
+		"content/z.md":        "# Zeta Guide\n\nText.\n",
+	})
+
+	opts := &Options{&RawOptions{
+		SiteDir:           tmp,
+		DeriveTitle:       true,
+		StripTitleHeading: true,
+		Markdown:          MarkdownConfig{WikiLinks: ptr(true)},
+	}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ":<p>See <a href=\"z.html\">Zeta Guide</a> and a[[0]].</p>\n"
+	if out := readFile(t, filepath.Join(tmp, "public/a.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+	expected = "Zeta Guide:<p>Text.</p>\n"
+	if out := readFile(t, filepath.Join(tmp, "public/z.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+}
+
+func TestRenderContentWikiLinksOffByDefault(t *testing.T) {
+	tmp := t.TempDir()
+	writeFiles(t, tmp, map[string]string{
+		"site.jsonr":          `{}`,
+		"templates/base.html": `{{.Content}}`,
+		"content/a.md":        "Index m[[i]] of [[a]].\n",
+	})
+
+	opts := &Options{&RawOptions{SiteDir: tmp, Strict: true}}
+	if err := renderContent(opts); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<p>Index m[[i]] of [[a]].</p>\n"
+	if out := readFile(t, filepath.Join(tmp, "public/a.html")); out != expected {
+		t.Fatalf("output diff:\n%s", strDiff(expected, out))
+	}
+}
@@ -0,0 +1,275 @@
+package check
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/msolo/yugo/internal/build"
+	"golang.org/x/net/html"
+)
+
+// Problem describes a single broken reference found in the rendered output.
+type Problem struct {
+	File string // output file relative to the checked directory
+	Line int
+	Ref  string // the reference exactly as written in the document
+	Msg  string
+
+	// The page the output file was built from, relative to the site
+	// directory, if any. See locate.
+	Source     string
+	SourceLine int
+}
+
+func (p Problem) String() string {
+	if p.Source == "" {
+		return fmt.Sprintf("%s:%d: %s → %s", p.File, p.Line, p.Msg, p.Ref)
+	}
+	src := p.Source
+	if p.SourceLine > 0 {
+		src = fmt.Sprintf("%s:%d", src, p.SourceLine)
+	}
+	return fmt.Sprintf("%s: %s → %s (in %s:%d)", src, p.Msg, p.Ref, p.File, p.Line)
+}
+
+// locate points a problem at the source of the page it was found in.
+// Rendering doesn't keep source positions, so the line is that of the first
+// mention of the reference in the source, as written or as the .md link it
+// was rewritten from, or else of its anchor. It stays 0 if there is none,
+// such as for a link from a template.
+func (p *Problem) locate(siteDir string, sources map[string]string) {
+	src, ok := sources[p.File]
+	if !ok {
+		return
+	}
+	p.Source = src
+	b, err := os.ReadFile(filepath.Join(siteDir, filepath.FromSlash(src)))
+	if err != nil {
+		return
+	}
+	refPath, frag, hasFrag := strings.Cut(p.Ref, "#")
+	candidates := []string{p.Ref}
+	if mdPath, ok := strings.CutSuffix(refPath, ".html"); ok {
+		md := mdPath + ".md"
+		if hasFrag {
+			md += "#" + frag
+		}
+		candidates = append(candidates, md)
+	}
+	if frag != "" {
+		candidates = append(candidates, "#"+frag)
+	}
+	for _, c := range candidates {
+		if i := bytes.Index(b, []byte(c)); i >= 0 {
+			p.SourceLine = 1 + bytes.Count(b[:i], []byte("\n"))
+			return
+		}
+	}
+}
+
+// ref is an internal reference found while scanning a document.
+type ref struct {
+	line int
+	val  string
+}
+
+// document is what we need to know about each output HTML file.
+type document struct {
+	ids  map[string]bool
+	refs []ref
+}
+
+func Run(opts *build.Options) {
+	problems, err := CheckDir(opts.OutDir())
+	if err != nil {
+		log.Fatal(err)
+	}
+	sources, err := build.Sources(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i := range problems {
+		problems[i].locate(opts.SiteDir(), sources)
+	}
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "%s\n", p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%d broken references found.\n", len(problems))
+		os.Exit(1)
+	}
+	fmt.Println("No broken references found.")
+}
+
+// CheckDir validates every internal href, src and srcset in the HTML files
+// under dir against the files present in dir and the element IDs in each
+// target document.
+func CheckDir(dir string) ([]Problem, error) {
+	files := map[string]bool{}
+	dirs := map[string]bool{}
+	docs := map[string]*document{}
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		files[rel] = true
+		for parent := path.Dir(rel); parent != "."; parent = path.Dir(parent) {
+			dirs[parent] = true
+		}
+
+		if strings.ToLower(filepath.Ext(p)) != ".html" {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		docs[rel] = scanDocument(b)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(docs))
+	for name := range docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	problems := []Problem{}
+	for _, name := range names {
+		for _, r := range docs[name].refs {
+			msg := resolve(name, r.val, files, dirs, docs)
+			if msg != "" {
+				problems = append(problems, Problem{File: name, Line: r.line, Ref: r.val, Msg: msg})
+			}
+		}
+	}
+	return problems, nil
+}
+
+// resolve checks a single reference from the document named src and returns
+// a description of the problem, or "" if the reference is fine.
+func resolve(src, val string, files, dirs map[string]bool, docs map[string]*document) string {
+	u, err := url.Parse(strings.TrimSpace(val))
+	if err != nil {
+		return "unparseable URL"
+	}
+	if u.Scheme != "" || u.Host != "" || u.Opaque != "" {
+		// External, mailto:, data: and friends are out of scope.
+		return ""
+	}
+
+	target := src
+	if u.Path != "" {
+		if strings.HasPrefix(u.Path, "/") {
+			target = path.Clean(strings.TrimPrefix(u.Path, "/"))
+		} else {
+			target = path.Clean(path.Join(path.Dir(src), u.Path))
+		}
+		if strings.HasPrefix(target, "../") || target == ".." {
+			return "reference escapes site root"
+		}
+		if !files[target] {
+			if target != "." && !dirs[target] {
+				return "broken link"
+			}
+			index := path.Join(target, "index.html")
+			if !files[index] {
+				return "directory has no index.html"
+			}
+			target = index
+		}
+	}
+
+	if u.Fragment == "" || u.Fragment == "top" {
+		return ""
+	}
+	doc, ok := docs[target]
+	if !ok {
+		// Anchors into non-HTML resources can't be checked.
+		return ""
+	}
+	if !doc.ids[u.Fragment] {
+		return "broken anchor"
+	}
+	return ""
+}
+
+// Attributes holding a single URL.
+var urlAttrs = map[string]bool{
+	"href":   true,
+	"src":    true,
+	"poster": true,
+}
+
+// scanDocument collects the element IDs and all internal references in a
+// document, recording the line each reference starts on.
+func scanDocument(b []byte) *document {
+	doc := &document{ids: map[string]bool{}}
+	z := html.NewTokenizer(bytes.NewReader(b))
+	line := 1
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return doc
+		}
+		tokLine := line
+		line += bytes.Count(z.Raw(), []byte("\n"))
+
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		tok := z.Token()
+		for _, a := range tok.Attr {
+			if a.Namespace != "" {
+				continue
+			}
+			switch {
+			case a.Key == "id":
+				doc.ids[a.Val] = true
+			case a.Key == "name" && tok.Data == "a":
+				doc.ids[a.Val] = true
+			case urlAttrs[a.Key]:
+				if a.Val != "" {
+					doc.refs = append(doc.refs, ref{line: tokLine, val: a.Val})
+				}
+			case a.Key == "srcset":
+				for _, u := range parseSrcset(a.Val) {
+					doc.refs = append(doc.refs, ref{line: tokLine, val: u})
+				}
+			}
+		}
+	}
+}
+
+// parseSrcset returns the URLs from a srcset attribute, discarding the
+// width and density descriptors.
+func parseSrcset(s string) []string {
+	urls := []string{}
+	for _, candidate := range strings.Split(s, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
@@ -0,0 +1,78 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/msolo/yugo/internal/testutil"
+)
+
+func TestCheckDir(t *testing.T) {
+	tmp := t.TempDir()
+	testutil.WriteFiles(t, tmp, map[string]string{
+		"index.html": `<html><body>
+<a href="docs/">docs</a>
+<a href="docs/intro.html#setup">setup</a>
+<a href="#top">top</a>
+<a href="https://example.com/missing.html">external</a>
+<img src="img/a.png" srcset="img/a.png 1x, img/a@2x.png 2x">
+<a href="docs/intro.html#nosuch">bad anchor</a>
+<a href="/missing.html">missing</a>
+</body></html>`,
+		"docs/index.html": `<html><body><a href="../index.html">home</a>
+<a href="intro.html">intro</a></body></html>`,
+		"docs/intro.html": `<html><body><h2 id="setup">Setup</h2>
+<a href="#setup">self</a>
+<a href="../empty/">empty</a></body></html>`,
+		"img/a.png":         "",
+		"empty/placeholder": "",
+	})
+
+	problems, err := CheckDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Problem{
+		{File: "docs/intro.html", Line: 3, Ref: "../empty/", Msg: "directory has no index.html"},
+		{File: "index.html", Line: 6, Ref: "img/a@2x.png", Msg: "broken link"},
+		{File: "index.html", Line: 7, Ref: "docs/intro.html#nosuch", Msg: "broken anchor"},
+		{File: "index.html", Line: 8, Ref: "/missing.html", Msg: "broken link"},
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i := range expected {
+		if problems[i] != expected[i] {
+			t.Errorf("problem %d: expected %v got %v", i, expected[i], problems[i])
+		}
+	}
+}
+
+func TestProblemLocate(t *testing.T) {
+	tmp := t.TempDir()
+	testutil.WriteFiles(t, tmp, map[string]string{
+		"content/docs/intro.md": "---\n{\"Title\": \"Intro\"}\n---\n# Intro\n\nSee [setup](setup.md#install).\n\nAnd [the top](#nosuch).\n",
+	})
+	sources := map[string]string{"docs/intro.html": "content/docs/intro.md"}
+
+	for _, tc := range []struct {
+		ref      string
+		expected string
+	}{
+		{"setup.html#install", "content/docs/intro.md:6: broken anchor → setup.html#install (in docs/intro.html:9)"},
+		{"#nosuch", "content/docs/intro.md:8: broken anchor → #nosuch (in docs/intro.html:9)"},
+		{"/css/main.css", "content/docs/intro.md: broken anchor → /css/main.css (in docs/intro.html:9)"},
+	} {
+		p := Problem{File: "docs/intro.html", Line: 9, Ref: tc.ref, Msg: "broken anchor"}
+		p.locate(tmp, sources)
+		if out := p.String(); out != tc.expected {
+			t.Errorf("expected %q got %q", tc.expected, out)
+		}
+	}
+
+	p := Problem{File: "static.html", Line: 2, Ref: "x.html", Msg: "broken link"}
+	p.locate(tmp, sources)
+	if out, expected := p.String(), "static.html:2: broken link → x.html"; out != expected {
+		t.Errorf("expected %q got %q", expected, out)
+	}
+}
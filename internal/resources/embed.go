@@ -10,7 +10,7 @@ import (
 // Anything here will eventually override user content, so we rely on
 // using namespaces to keep things from conflicting as much as possible.
 
-//go:embed all:root all:example
+//go:embed all:root all:example all:templates
 var EmbeddedResources embed.FS
 
 // Turn it into a sub-FS rooted at `root/`
@@ -19,6 +19,10 @@ var RootFS fs.FS
 // Turn it into a sub-FS rooted at `example/`
 var ExampleFS fs.FS
 
+// Turn it into a sub-FS rooted at `templates/`. These are built-in templates
+// that a site can override by creating a template with the same name.
+var TemplatesFS fs.FS
+
 func init() {
 	sub, err := fs.Sub(EmbeddedResources, "root")
 	if err != nil {
@@ -31,4 +35,10 @@ func init() {
 		log.Fatalf("unable to initialize embedded resources: %v", err)
 	}
 	ExampleFS = sub
+
+	sub, err = fs.Sub(EmbeddedResources, "templates")
+	if err != nil {
+		log.Fatalf("unable to initialize embedded resources: %v", err)
+	}
+	TemplatesFS = sub
 }
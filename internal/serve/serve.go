@@ -21,8 +21,10 @@ func noCache(h http.Handler) http.Handler {
 }
 
 func Run(opts *build.Options) {
+	// Files outside the site directory that the last build read.
+	var deps []string
 	builder := func() {
-		build.Run(opts, nil)
+		deps = build.Run(opts, nil)
 	}
 
 	var clients sync.Map
@@ -66,12 +68,14 @@ func Run(opts *build.Options) {
 		_ = watcher.Close()
 	}()
 
+	watcher.WatchFiles(deps)
 	watcher.Start()
 
 	for {
 		<-watcher.Events()
 		fmt.Println("🔄 Change detected — rebuilding...")
 		builder()
+		watcher.WatchFiles(deps)
 
 		if opts.LiveReload() {
 			// Send a reload message to all WS clients
@@ -79,6 +79,16 @@ func (w *Watcher) addDirRecursive(root string) error {
 	})
 }
 
+// WatchFiles adds the directories of files outside the watched paths, like
+// Markdown included from elsewhere in the repository.
+func (w *Watcher) WatchFiles(files []string) {
+	for _, f := range files {
+		if err := w.w.Add(filepath.Dir(f)); err != nil {
+			fmt.Fprintf(os.Stderr, "WARN: failed to watch %s: %v\n", f, err)
+		}
+	}
+}
+
 func (w *Watcher) Events() <-chan struct{} {
 	return w.trigger
 }